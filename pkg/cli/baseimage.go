@@ -27,14 +27,20 @@ func NewBaseImageRootCommand() (*cobra.Command, error) {
 		Short:   "Cog base image commands. This is an experimental feature with no guarantees of future support.",
 		Version: fmt.Sprintf("%s (built %s)", global.Version, global.BuildTime),
 		// This stops errors being printed because we print them in cmd/cog/cog.go
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if global.Debug {
 				console.SetLevel(console.DebugLevel)
+			} else if global.Quiet {
+				console.SetLevel(console.WarnLevel)
 			}
 			cmd.SilenceUsage = true
+			if err := console.SetColorMode(global.Color); err != nil {
+				return err
+			}
 			if err := update.DisplayAndCheckForRelease(); err != nil {
 				console.Debugf("%s", err)
 			}
+			return nil
 		},
 		SilenceErrors: true,
 	}
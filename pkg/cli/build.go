@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 
 	"github.com/replicate/cog/pkg/config"
 	"github.com/replicate/cog/pkg/image"
@@ -22,13 +22,19 @@ var buildSchemaFile string
 var buildUseCudaBaseImage string
 var buildDockerfileFile string
 var buildUseCogBaseImage bool
+var buildSquash bool
+var buildOutputOCI string
+var buildRequirementsLock string
+var buildAnnotations []string
+var buildCompressSchemaLabel bool
+var buildStrictOutputs bool
 
 func newBuildCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "build",
 		Short:   "Build an image from cog.yaml",
 		Args:    cobra.NoArgs,
-		RunE:    buildCommand,
+		RunE:    withJSONErrors(buildCommand),
 		PreRunE: checkMutuallyExclusiveFlags,
 	}
 	addBuildProgressOutputFlag(cmd)
@@ -39,20 +45,34 @@ func newBuildCommand() *cobra.Command {
 	addUseCudaBaseImageFlag(cmd)
 	addDockerfileFlag(cmd)
 	addUseCogBaseImageFlag(cmd)
+	addSquashFlag(cmd)
+	addCompressSchemaLabelFlag(cmd)
+	addStrictFlag(cmd)
 	addBuildTimestampFlag(cmd)
+	addAnnotationFlag(cmd)
+	addJSONFlag(cmd)
 	cmd.Flags().StringVarP(&buildTag, "tag", "t", "", "A name for the built image in the form 'repository:tag'")
+	cmd.Flags().StringVar(&buildOutputOCI, "output-oci", "", "Also write the built image to this path as an OCI image layout tarball")
+	cmd.Flags().StringVar(&buildRequirementsLock, "requirements-lock", "", "Write the exact versions of the Python packages installed in the image to this path, in requirements.txt format")
 	return cmd
 }
 
 func buildCommand(cmd *cobra.Command, args []string) error {
+	startJSONOutput()
+
+	buildOptions, err := buildOptionsFromFlags()
+	if err != nil {
+		return err
+	}
+
 	cfg, projectDir, err := config.GetConfig(projectDirFlag)
 	if err != nil {
 		return err
 	}
 
-	imageName := cfg.Image
-	if buildTag != "" {
-		imageName = buildTag
+	imageName, err := cfg.ImageName(buildTag)
+	if err != nil {
+		return err
 	}
 	if imageName == "" {
 		imageName = config.DockerImageName(projectDir)
@@ -63,21 +83,77 @@ func buildCommand(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := image.Build(cfg, projectDir, imageName, buildSecrets, buildNoCache, buildSeparateWeights, buildUseCudaBaseImage, buildProgressOutput, buildSchemaFile, buildDockerfileFile, buildUseCogBaseImage); err != nil {
+	annotations, err := parseAnnotations(buildAnnotations)
+	if err != nil {
+		return err
+	}
+	if len(annotations) > 0 && buildOutputOCI == "" {
+		return fmt.Errorf("Annotations are added to the image manifest, which Docker doesn't store locally. Use --annotation with --output-oci, or with cog push")
+	}
+
+	start := time.Now()
+	if err := image.Build(cfg, projectDir, imageName, buildOptions); err != nil {
 		return err
 	}
+	buildDuration := time.Since(start)
 
 	console.Infof("\nImage built as %s", imageName)
 
+	if buildOutputOCI != "" {
+		if err := image.ExportOCI(imageName, buildOutputOCI, annotations); err != nil {
+			return err
+		}
+		console.Infof("Image written to %s", buildOutputOCI)
+	}
+
+	if buildRequirementsLock != "" {
+		if err := image.WriteRequirementsLock(imageName, buildRequirementsLock); err != nil {
+			return err
+		}
+		console.Infof("Python package versions written to %s", buildRequirementsLock)
+	}
+
+	if buildJSON {
+		result, err := newBuildResult(imageName, buildDuration)
+		if err != nil {
+			return err
+		}
+		return writeJSON(result)
+	}
+
 	return nil
 }
 
+// buildOptionsFromFlags returns the options for image.Build from the flags shared by cog
+// build and cog push
+func buildOptionsFromFlags() (image.BuildOptions, error) {
+	if buildSquash && buildSeparateWeights {
+		return image.BuildOptions{}, fmt.Errorf("--squash can't be used with --separate-weights, because squashing would merge the weights back into the same layer as the code")
+	}
+	return image.BuildOptions{
+		Secrets:             buildSecrets,
+		NoCache:             buildNoCache,
+		SeparateWeights:     buildSeparateWeights,
+		UseCudaBaseImage:    buildUseCudaBaseImage,
+		ProgressOutput:      buildProgressOutput,
+		SchemaFile:          buildSchemaFile,
+		DockerfileFile:      buildDockerfileFile,
+		UseCogBaseImage:     buildUseCogBaseImage,
+		Squash:              buildSquash,
+		CompressSchemaLabel: buildCompressSchemaLabel,
+		StrictOutputs:       buildStrictOutputs,
+	}, nil
+}
+
 func addBuildProgressOutputFlag(cmd *cobra.Command) {
 	defaultOutput := "auto"
 	if os.Getenv("TERM") == "dumb" {
 		defaultOutput = "plain"
 	}
-	cmd.Flags().StringVar(&buildProgressOutput, "progress", defaultOutput, "Set type of build progress output, 'auto' (default), 'tty' or 'plain'")
+	if progress := os.Getenv("BUILDKIT_PROGRESS"); progress != "" {
+		defaultOutput = progress
+	}
+	cmd.Flags().StringVar(&buildProgressOutput, "progress", defaultOutput, "Set type of build progress output, 'auto' (default), 'tty', 'plain', or 'json'")
 }
 
 func addSecretsFlag(cmd *cobra.Command) {
@@ -101,23 +177,46 @@ func addUseCudaBaseImageFlag(cmd *cobra.Command) {
 }
 
 func addDockerfileFlag(cmd *cobra.Command) {
-	cmd.Flags().StringVar(&buildDockerfileFile, "dockerfile", "", "Path to a Dockerfile. If set, cog will use this Dockerfile instead of generating one from cog.yaml")
-	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		if f.Name == "dockerfile" {
-			f.Hidden = true
-		}
-	})
+	cmd.Flags().StringVar(&buildDockerfileFile, "dockerfile", "", "Path to a Dockerfile. If set, cog will build this Dockerfile instead of generating one from cog.yaml, then add Cog's labels and schema to the image as usual")
 }
 
 func addUseCogBaseImageFlag(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&buildUseCogBaseImage, "use-cog-base-image", false, "Use pre-built Cog base image for faster cold boots")
 }
 
+func addSquashFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildSquash, "squash", false, "Squash the built image into a single layer")
+}
+
+func addCompressSchemaLabelFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildCompressSchemaLabel, "compress-schema-label", false, "Gzip and base64-encode the OpenAPI schema in the run.cog.openapi_schema label, for models with very large schemas")
+}
+
+func addStrictFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildStrictOutputs, "strict", false, "Fail if the model's output schema is opaque, like a bare dict or Any, instead of describing what the model returns. The schema comes from running the built image, so this is checked after the image is built, and before it's pushed")
+}
+
 func addBuildTimestampFlag(cmd *cobra.Command) {
 	cmd.Flags().Int64Var(&config.BuildSourceEpochTimestamp, "timestamp", -1, "Number of seconds sing Epoch to use for the build timestamp; this rewrites the timestamp of each layer. Useful for reproducibility. (`-1` to disable timestamp rewrites)")
 	_ = cmd.Flags().MarkHidden("timestamp")
 }
 
+func addAnnotationFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&buildAnnotations, "annotation", []string{}, "Add an OCI annotation to the image manifest in the form 'key=value'. Unlike labels, annotations aren't part of the image config")
+}
+
+func parseAnnotations(flags []string) (map[string]string, error) {
+	annotations := map[string]string{}
+	for _, flag := range flags {
+		key, value, ok := strings.Cut(flag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("Invalid annotation %q. Annotations must be in the form 'key=value'", flag)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
 func checkMutuallyExclusiveFlags(cmd *cobra.Command, args []string) error {
 	flags := []string{"use-cog-base-image", "use-cuda-base-image", "dockerfile"}
 	var flagsSet []string
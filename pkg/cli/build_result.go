@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/replicate/cog/pkg/docker"
+	"github.com/replicate/cog/pkg/global"
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+var buildJSON bool
+
+// buildResult is what build and push write to stdout with --json
+type buildResult struct {
+	Image string `json:"image"`
+	ID    string `json:"id"`
+	// Digest is the digest of the pushed manifest, so it's only set by push
+	Digest        string  `json:"digest,omitempty"`
+	Size          int64   `json:"size"`
+	BuildDuration float64 `json:"build_duration_seconds"`
+	Platform      string  `json:"platform"`
+}
+
+func addJSONFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildJSON, "json", false, "Write the resulting image to stdout as JSON, and only show warnings and errors while building")
+}
+
+// startJSONOutput keeps stdout clean for the JSON result by only logging warnings and
+// errors, and not showing Docker's progress
+func startJSONOutput() {
+	if buildJSON {
+		global.Quiet = true
+		console.SetLevel(console.WarnLevel)
+	}
+}
+
+func newBuildResult(imageName string, buildDuration time.Duration) (*buildResult, error) {
+	inspect, err := docker.ImageInspect(imageName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to inspect %s: %w", imageName, err)
+	}
+	platform := inspect.Os + "/" + inspect.Architecture
+	if inspect.Variant != "" {
+		platform += "/" + inspect.Variant
+	}
+	return &buildResult{
+		Image:         imageName,
+		ID:            inspect.ID,
+		Size:          inspect.Size,
+		BuildDuration: buildDuration.Seconds(),
+		Platform:      platform,
+	}, nil
+}
+
+func writeJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to encode JSON: %w", err)
+	}
+	console.Output(string(out))
+	return nil
+}
+
+// withJSONErrors wraps run so that with --json, errors are written to stdout as JSON too.
+// They're still returned, so they're logged and cog exits with an error.
+func withJSONErrors(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := run(cmd, args)
+		if err != nil && buildJSON {
+			if jsonErr := writeJSON(map[string]string{"error": err.Error()}); jsonErr != nil {
+				console.Debugf("%s", jsonErr)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	annotations, err := parseAnnotations([]string{
+		"org.opencontainers.image.source=https://github.com/user/model",
+		"com.example.note=a=b",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"org.opencontainers.image.source": "https://github.com/user/model",
+		"com.example.note":                "a=b",
+	}, annotations)
+
+	_, err = parseAnnotations([]string{"no-value"})
+	require.ErrorContains(t, err, `Invalid annotation "no-value"`)
+}
+
+func TestBuildRejectsSquashWithSeparateWeights(t *testing.T) {
+	t.Setenv("COG_NO_UPDATE_CHECK", "1")
+	chdirTemp(t)
+
+	for _, command := range []string{"build", "push"} {
+		cmd, err := NewRootCommand()
+		require.NoError(t, err)
+		cmd.SetArgs([]string{command, "--squash", "--separate-weights"})
+
+		captureOutput(t, func() {
+			err = cmd.Execute()
+		})
+		require.ErrorContains(t, err, "--squash can't be used with --separate-weights", command)
+	}
+}
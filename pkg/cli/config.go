@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/replicate/cog/pkg/config"
+	"github.com/replicate/cog/pkg/global"
+)
+
+var configShowFormat string
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect " + global.ConfigFilename,
+	}
+	cmd.AddCommand(newConfigShowCommand())
+	return cmd
+}
+
+func newConfigShowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the resolved configuration, with defaults and auto-detected versions applied",
+		Long: `Print the resolved configuration, with defaults and auto-detected versions applied.
+
+Like the config label on built images, it leaves out run env and Python package
+indexes, because they can have credentials in them.`,
+		RunE: cmdConfigShow,
+		Args: cobra.NoArgs,
+	}
+	cmd.Flags().StringVar(&configShowFormat, "format", "yaml", "Output format: yaml or json")
+	return cmd
+}
+
+func cmdConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, projectDir, err := config.GetConfig(projectDirFlag)
+	if err != nil {
+		return err
+	}
+	if cfg.Image == "" {
+		cfg.Image = config.DockerImageName(projectDir)
+	}
+
+	data, err := cfg.Marshal(configShowFormat)
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
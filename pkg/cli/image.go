@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/replicate/cog/pkg/image"
+	"github.com/replicate/cog/pkg/registry"
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+var imageDiffJSON bool
+
+func newImageCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "Inspect images that have been pushed to a registry",
+	}
+	cmd.AddCommand(
+		newImageDiffCommand(),
+	)
+	return cmd
+}
+
+func newImageDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff <image> <image>",
+		Short:   "Show the differences between two images' layers and config",
+		Example: "  cog image diff r8.im/your-username/your-model:v1 r8.im/your-username/your-model:v2",
+		RunE:    imageDiff,
+		Args:    cobra.ExactArgs(2),
+	}
+	cmd.Flags().BoolVar(&imageDiffJSON, "json", false, "Write the differences to stdout as JSON")
+	// Only for reading images from a registry. Base images in builds are pulled by Docker,
+	// which has its own registry-mirrors setting.
+	cmd.Flags().StringArrayVar(&registry.Mirrors, "registry-mirror", nil, "A registry host to read the images from before their own registry, if it's rate limiting, failing, or unreachable. Can be repeated. For base images in builds, set registry-mirrors in Docker's daemon.json instead")
+	return cmd
+}
+
+func imageDiff(cmd *cobra.Command, args []string) error {
+	refA, refB := args[0], args[1]
+	changes, err := image.Diff(cmd.Context(), refA, refB)
+	if err != nil {
+		return err
+	}
+
+	if imageDiffJSON {
+		out, err := json.MarshalIndent(map[string]interface{}{"a": refA, "b": refB, "changes": changes}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to encode diff: %w", err)
+		}
+		console.Output(string(out))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		console.Infof("%s and %s are the same", refA, refB)
+		return nil
+	}
+	for _, change := range changes {
+		console.Output(formatChange(change))
+	}
+	return nil
+}
+
+func formatChange(change image.Change) string {
+	field := change.Field
+	if change.Key != "" {
+		field += " " + change.Key
+	}
+	switch {
+	case change.A == nil:
+		return fmt.Sprintf("+ %s: %s", field, *change.B)
+	case change.B == nil:
+		return fmt.Sprintf("- %s: %s", field, *change.A)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", field, *change.A, *change.B)
+	}
+}
@@ -9,11 +9,11 @@ import (
 )
 
 func TestInit(t *testing.T) {
-	dir := t.TempDir()
-
-	require.NoError(t, os.Chdir(dir))
+	chdirTemp(t)
+	dir, err := os.Getwd()
+	require.NoError(t, err)
 
-	err := initCommand([]string{})
+	err = initCommand([]string{})
 	require.NoError(t, err)
 
 	require.FileExists(t, path.Join(dir, ".dockerignore"))
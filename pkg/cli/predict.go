@@ -24,11 +24,15 @@ import (
 )
 
 var (
-	envFlags   []string
-	inputFlags []string
-	outPath    string
+	envFlags    []string
+	inputFlags  []string
+	outPath     string
+	predictJSON bool
 )
 
+// maxJSONLogLines is how many lines of logs from the end of a failed prediction are included in --json output
+const maxJSONLogLines = 50
+
 func newPredictCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "predict [image]",
@@ -54,6 +58,7 @@ the prediction on that.`,
 	cmd.Flags().StringArrayVarP(&inputFlags, "input", "i", []string{}, "Inputs, in the form name=value. if value is prefixed with @, then it is read from a file on disk. E.g. -i path=@image.jpg")
 	cmd.Flags().StringVarP(&outPath, "output", "o", "", "Output path")
 	cmd.Flags().StringArrayVarP(&envFlags, "env", "e", []string{}, "Environment variables, in the form name=value")
+	cmd.Flags().BoolVar(&predictJSON, "json", false, "Write the prediction to stdout as JSON, including the error and the end of the logs if it fails")
 
 	return cmd
 }
@@ -180,9 +185,15 @@ func predictIndividualInputs(predictor predict.Predictor, inputFlags []string, o
 	}
 
 	prediction, err := predictor.Predict(inputs)
+	if predictJSON {
+		return writePredictionJSON(prediction, err)
+	}
 	if err != nil {
 		return err
 	}
+	if prediction.Status == predict.StatusFailed {
+		return fmt.Errorf("Prediction failed: %s", prediction.Error)
+	}
 
 	// Generate output depending on type in schema
 	var out []byte
@@ -244,6 +255,49 @@ func predictIndividualInputs(predictor predict.Predictor, inputFlags []string, o
 	return writeOutput(outputPath, out)
 }
 
+type predictionJSONOutput struct {
+	Status string       `json:"status"`
+	Output *interface{} `json:"output,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Logs   string       `json:"logs,omitempty"`
+}
+
+// writePredictionJSON writes the result of a prediction to stdout as JSON. If the prediction
+// couldn't be run or failed, it returns an error so cog exits with a non-zero status.
+func writePredictionJSON(prediction *predict.Response, predictErr error) error {
+	result := predictionJSONOutput{Status: string(predict.StatusFailed)}
+	if predictErr != nil {
+		result.Error = predictErr.Error()
+	} else {
+		result.Status = string(prediction.Status)
+		result.Output = prediction.Output
+		result.Error = prediction.Error
+		if prediction.Status != predict.StatusSucceeded {
+			result.Logs = tailLines(prediction.Logs, maxJSONLogLines)
+		}
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to encode prediction as JSON: %w", err)
+	}
+	console.Output(string(out))
+
+	if result.Status != string(predict.StatusSucceeded) {
+		return fmt.Errorf("Prediction failed: %s", result.Error)
+	}
+	return nil
+}
+
+// tailLines returns the last n lines of s
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func writeOutput(outputPath string, output []byte) error {
 	outputPath, err := homedir.Expand(outputPath)
 	if err != nil {
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/predict"
+)
+
+func TestWritePredictionJSONFailed(t *testing.T) {
+	logs := []string{}
+	for i := 0; i < maxJSONLogLines+10; i++ {
+		logs = append(logs, fmt.Sprintf("line %d", i))
+	}
+	prediction := &predict.Response{
+		Status: predict.StatusFailed,
+		Error:  "ValueError: bad input",
+		Logs:   strings.Join(logs, "\n") + "\n",
+	}
+
+	var err error
+	stdout, _ := captureOutput(t, func() {
+		err = writePredictionJSON(prediction, nil)
+	})
+	require.ErrorContains(t, err, "Prediction failed: ValueError: bad input")
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	require.Equal(t, "failed", result["status"])
+	require.Equal(t, "ValueError: bad input", result["error"])
+	require.NotContains(t, result, "output")
+	resultLogs := strings.Split(result["logs"].(string), "\n")
+	require.Len(t, resultLogs, maxJSONLogLines)
+	require.Equal(t, fmt.Sprintf("line %d", maxJSONLogLines+9), resultLogs[len(resultLogs)-1])
+}
+
+func TestWritePredictionJSONRequestError(t *testing.T) {
+	var err error
+	stdout, _ := captureOutput(t, func() {
+		err = writePredictionJSON(nil, errors.New("/predictions call returned status 500"))
+	})
+	require.Error(t, err)
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	require.Equal(t, map[string]any{
+		"status": "failed",
+		"error":  "/predictions call returned status 500",
+	}, result)
+}
+
+func TestWritePredictionJSONSucceeded(t *testing.T) {
+	var output any = "hello world"
+	prediction := &predict.Response{
+		Status: predict.StatusSucceeded,
+		Output: &output,
+		Logs:   "some logs\n",
+	}
+
+	var err error
+	stdout, _ := captureOutput(t, func() {
+		err = writePredictionJSON(prediction, nil)
+	})
+	require.NoError(t, err)
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	require.Equal(t, map[string]any{
+		"status": "succeeded",
+		"output": "hello world",
+	}, result)
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/replicate/cog/pkg/docker"
 	"github.com/replicate/cog/pkg/global"
 	"github.com/replicate/cog/pkg/image"
+	"github.com/replicate/cog/pkg/registry"
 	"github.com/replicate/cog/pkg/util/console"
 )
 
@@ -19,7 +21,7 @@ func newPushCommand() *cobra.Command {
 
 		Short:   "Build and push model in current directory to a Docker registry",
 		Example: `cog push r8.im/your-username/hotdog-detector`,
-		RunE:    push,
+		RunE:    withJSONErrors(push),
 		Args:    cobra.MaximumNArgs(1),
 	}
 	addSecretsFlag(cmd)
@@ -30,25 +32,51 @@ func newPushCommand() *cobra.Command {
 	addDockerfileFlag(cmd)
 	addBuildProgressOutputFlag(cmd)
 	addUseCogBaseImageFlag(cmd)
+	addSquashFlag(cmd)
+	addCompressSchemaLabelFlag(cmd)
+	addStrictFlag(cmd)
+	addAnnotationFlag(cmd)
+	addJSONFlag(cmd)
+	// Only used when cog uploads the image itself, because docker push has its own limit
+	cmd.Flags().IntVar(&registry.PushConcurrency, "push-concurrency", registry.PushConcurrency, "How many layers to upload at once when pushing with --annotation")
 
 	return cmd
 }
 
 func push(cmd *cobra.Command, args []string) error {
+	startJSONOutput()
+
+	buildOptions, err := buildOptionsFromFlags()
+	if err != nil {
+		return err
+	}
+
 	cfg, projectDir, err := config.GetConfig(projectDirFlag)
 	if err != nil {
 		return err
 	}
 
-	imageName := cfg.Image
+	override := ""
 	if len(args) > 0 {
-		imageName = args[0]
+		override = args[0]
+	}
+	imageName, err := cfg.ImageName(override)
+	if err != nil {
+		return err
 	}
 
 	if imageName == "" {
 		return fmt.Errorf("To push images, you must either set the 'image' option in cog.yaml or pass an image name as an argument. For example, 'cog push r8.im/your-username/hotdog-detector'")
 	}
 
+	annotations, err := parseAnnotations(buildAnnotations)
+	if err != nil {
+		return err
+	}
+	if registry.PushConcurrency < 1 {
+		return fmt.Errorf("--push-concurrency must be at least 1, got %d", registry.PushConcurrency)
+	}
+
 	replicatePrefix := fmt.Sprintf("%s/", global.ReplicateRegistryHost)
 	if strings.HasPrefix(imageName, replicatePrefix) {
 		if err := docker.ManifestInspect(imageName); err != nil && strings.Contains(err.Error(), `"code":"NAME_UNKNOWN"`) {
@@ -56,13 +84,21 @@ func push(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if err := image.Build(cfg, projectDir, imageName, buildSecrets, buildNoCache, buildSeparateWeights, buildUseCudaBaseImage, buildProgressOutput, buildSchemaFile, buildDockerfileFile, buildUseCogBaseImage); err != nil {
+	start := time.Now()
+	if err := image.Build(cfg, projectDir, imageName, buildOptions); err != nil {
 		return err
 	}
+	buildDuration := time.Since(start)
 
 	console.Infof("\nPushing image '%s'...", imageName)
 
-	exitStatus := docker.Push(imageName)
+	var exitStatus error
+	if len(annotations) > 0 {
+		// docker push can't add annotations, so push the image ourselves
+		exitStatus = image.PushWithAnnotations(cmd.Context(), imageName, annotations)
+	} else {
+		exitStatus = docker.Push(imageName)
+	}
 	if exitStatus == nil {
 		console.Infof("Image '%s' pushed", imageName)
 		if strings.HasPrefix(imageName, replicatePrefix) {
@@ -70,5 +106,17 @@ func push(cmd *cobra.Command, args []string) error {
 			console.Infof("\nRun your model on Replicate:\n    %s", replicatePage)
 		}
 	}
-	return exitStatus
+	if exitStatus != nil || !buildJSON {
+		return exitStatus
+	}
+
+	result, err := newBuildResult(imageName, buildDuration)
+	if err != nil {
+		return err
+	}
+	result.Digest, err = registry.Digest(cmd.Context(), imageName)
+	if err != nil {
+		return err
+	}
+	return writeJSON(result)
 }
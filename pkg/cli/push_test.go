@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushRequiresImageName(t *testing.T) {
+	t.Setenv("COG_NO_UPDATE_CHECK", "1")
+	chdirTemp(t)
+	require.NoError(t, os.WriteFile("cog.yaml", []byte("build:\n  python_version: \"3.11\"\n"), 0o644))
+
+	cmd, err := NewRootCommand()
+	require.NoError(t, err)
+	cmd.SetArgs([]string{"push"})
+
+	captureOutput(t, func() {
+		err = cmd.Execute()
+	})
+	require.ErrorContains(t, err, "you must either set the 'image' option in cog.yaml or pass an image name as an argument")
+}
+
+func TestPushRejectsInvalidImageName(t *testing.T) {
+	t.Setenv("COG_NO_UPDATE_CHECK", "1")
+	chdirTemp(t)
+	require.NoError(t, os.WriteFile("cog.yaml", []byte("build:\n  python_version: \"3.11\"\nimage: r8.im/user/model\n"), 0o644))
+
+	cmd, err := NewRootCommand()
+	require.NoError(t, err)
+	cmd.SetArgs([]string{"push", "r8.im/User/Model"})
+
+	captureOutput(t, func() {
+		err = cmd.Execute()
+	})
+	require.ErrorContains(t, err, `Invalid image name "r8.im/User/Model"`)
+}
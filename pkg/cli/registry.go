@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/replicate/cog/pkg/registry"
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+var registryRmYes bool
+
+func newRegistryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry",
+		Short: "Manage images in a registry",
+	}
+	cmd.AddCommand(
+		newRegistryTagsCommand(),
+		newRegistryRmCommand(),
+	)
+	return cmd
+}
+
+func newRegistryTagsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "tags <repository>",
+		Short:   "List the tags in a repository, and the digests they point to",
+		Example: "  cog registry tags r8.im/your-username/your-model",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo := args[0]
+			tags, err := registry.ListTags(cmd.Context(), repo)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(tags))
+			for _, tag := range tags {
+				digest, err := registry.Digest(cmd.Context(), repo+":"+tag)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, []string{tag, digest})
+			}
+			console.Table([]string{"TAG", "DIGEST"}, rows)
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+}
+
+func newRegistryRmCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rm <image>",
+		Short:   "Delete a tag or manifest from a registry",
+		Example: "  cog registry rm r8.im/your-username/your-model:v1",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref := args[0]
+			if !registryRmYes {
+				ok, err := console.InteractiveBool{
+					Prompt:         fmt.Sprintf("Delete %s from the registry?", ref),
+					Default:        false,
+					NonDefaultFlag: "--yes",
+				}.Read()
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+			}
+			if err := registry.Delete(cmd.Context(), ref); err != nil {
+				return err
+			}
+			console.Infof("Deleted %s", ref)
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVarP(&registryRmYes, "yes", "y", false, "Don't ask for confirmation")
+	return cmd
+}
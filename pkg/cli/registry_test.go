@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/registry"
+)
+
+func TestRegistryTagsListsDigests(t *testing.T) {
+	t.Setenv("COG_NO_UPDATE_CHECK", "1")
+	server := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	repo := u.Host + "/user/model"
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	require.NoError(t, registry.Write(context.Background(), repo+":v1", img))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	cmd, err := NewRootCommand()
+	require.NoError(t, err)
+	cmd.SetArgs([]string{"registry", "tags", repo})
+
+	stdout, _ := captureOutput(t, func() {
+		require.NoError(t, cmd.Execute())
+	})
+	// stdout isn't a terminal, so the table is tab-separated
+	require.Equal(t, "TAG\tDIGEST\nv1\t"+digest.String()+"\n", stdout)
+}
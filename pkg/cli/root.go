@@ -24,14 +24,20 @@ https://github.com/replicate/cog`,
       $ cog run echo hello world`,
 		Version: fmt.Sprintf("%s (built %s)", global.Version, global.BuildTime),
 		// This stops errors being printed because we print them in cmd/cog/cog.go
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if global.Debug {
 				console.SetLevel(console.DebugLevel)
+			} else if global.Quiet {
+				console.SetLevel(console.WarnLevel)
 			}
 			cmd.SilenceUsage = true
+			if err := console.SetColorMode(global.Color); err != nil {
+				return err
+			}
 			if err := update.DisplayAndCheckForRelease(); err != nil {
 				console.Debugf("%s", err)
 			}
+			return nil
 		},
 		SilenceErrors: true,
 	}
@@ -39,11 +45,14 @@ https://github.com/replicate/cog`,
 
 	rootCmd.AddCommand(
 		newBuildCommand(),
+		newConfigCommand(),
 		newDebugCommand(),
+		newImageCommand(),
 		newInitCommand(),
 		newLoginCommand(),
 		newPredictCommand(),
 		newPushCommand(),
+		newRegistryCommand(),
 		newRunCommand(),
 		newTrainCommand(),
 	)
@@ -53,6 +62,8 @@ https://github.com/replicate/cog`,
 
 func setPersistentFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVar(&global.Debug, "debug", false, "Show debugging output")
+	cmd.PersistentFlags().BoolVarP(&global.Quiet, "quiet", "q", false, "Only show warnings and errors")
+	cmd.PersistentFlags().StringVar(&global.Color, "color", console.ColorAuto, "When to color output: auto, always, or never. auto respects NO_COLOR")
 	cmd.PersistentFlags().BoolVar(&global.ProfilingEnabled, "profile", false, "Enable profiling")
 	cmd.PersistentFlags().Bool("version", false, "Show version of Cog")
 	_ = cmd.PersistentFlags().MarkHidden("profile")
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/global"
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+func TestQuietSuppressesOutput(t *testing.T) {
+	t.Setenv("COG_NO_UPDATE_CHECK", "1")
+	chdirTemp(t)
+	t.Cleanup(func() {
+		global.Quiet = false
+		console.SetLevel(console.InfoLevel)
+	})
+
+	cmd, err := NewRootCommand()
+	require.NoError(t, err)
+	cmd.SetArgs([]string{"init", "--quiet"})
+
+	stdout, stderr := captureOutput(t, func() {
+		require.NoError(t, cmd.Execute())
+	})
+	require.Empty(t, stdout)
+	require.Empty(t, stderr)
+	require.FileExists(t, "cog.yaml")
+}
+
+// chdirTemp changes to a new temporary directory for the rest of the test, and changes
+// back afterwards so later tests don't run in a deleted directory
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+}
+
+func captureOutput(t *testing.T, f func()) (stdout string, stderr string) {
+	t.Helper()
+	outR, outW, err := os.Pipe()
+	require.NoError(t, err)
+	errR, errW, err := os.Pipe()
+	require.NoError(t, err)
+
+	origStdout, origStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outW, errW
+	defer func() {
+		os.Stdout, os.Stderr = origStdout, origStderr
+	}()
+
+	f()
+
+	require.NoError(t, outW.Close())
+	require.NoError(t, errW.Close())
+	outBytes, err := io.ReadAll(outR)
+	require.NoError(t, err)
+	errBytes, err := io.ReadAll(errR)
+	require.NoError(t, err)
+	return string(outBytes), string(errBytes)
+}
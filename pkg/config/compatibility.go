@@ -50,6 +50,16 @@ func (compat *TFCompatibility) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+type JaxCompatibility struct {
+	Jax string
+	// JaxlibPackage is the pip requirement for the CUDA build of jaxlib, e.g. jaxlib==0.4.20+cuda12.cudnn89
+	JaxlibPackage string
+	FindLinks     string
+	CUDA          string
+	CuDNN         string
+	Pythons       []string
+}
+
 type TorchCompatibility struct {
 	Torch         string
 	Torchvision   string
@@ -57,7 +67,9 @@ type TorchCompatibility struct {
 	FindLinks     string
 	ExtraIndexURL string
 	CUDA          *string
-	Pythons       []string
+	// ROCm is the ROCm version for AMD GPU builds of torch. CUDA is nil when it's set.
+	ROCm    *string
+	Pythons []string
 }
 
 func (c *TorchCompatibility) TorchVersion() string {
@@ -117,6 +129,10 @@ func init() {
 	}
 	filteredTorchCompatibilityMatrix := []TorchCompatibility{}
 	for _, compat := range torchCompatibilityMatrix {
+		// There aren't ROCm base images yet, and ROCm builds would otherwise look like CPU builds
+		if compat.ROCm != nil {
+			continue
+		}
 		for _, cudaBaseImage := range CUDABaseImages {
 			if compat.CUDA == nil || version.Matches(*compat.CUDA, cudaBaseImage.CUDA) {
 				filteredTorchCompatibilityMatrix = append(filteredTorchCompatibilityMatrix, compat)
@@ -336,6 +352,107 @@ func torchGPUPackage(ver string, cuda string) (name, cpuVersion, findLinks, extr
 	return "torch", latest.Torch, latest.FindLinks, latest.ExtraIndexURL, nil
 }
 
+// ResolveTorchCompatibility returns the entry in the compatibility matrix that will be
+// installed for torch with cuda and python, or an error describing the combinations that
+// would work. cuda is empty for CPU builds.
+func ResolveTorchCompatibility(torch string, cuda string, python string) (*TorchCompatibility, error) {
+	compats := torchCompatibilitiesFor(torch)
+	if len(compats) == 0 {
+		return nil, fmt.Errorf("Cog doesn't know about torch==%s. The nearest versions it knows about are: %s", torch, strings.Join(nearestTorchVersions(torch, 5), ", "))
+	}
+
+	if python != "" {
+		major, minor, err := splitPythonVersion(python)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Python version %q: %w", python, err)
+		}
+		python = fmt.Sprintf("%d.%d", major, minor)
+	}
+
+	// Same as torchGPUPackage: the latest CUDA that is at most as high as the requested one
+	var match *TorchCompatibility
+	for i, compat := range compats {
+		switch {
+		case cuda == "":
+			if compat.CUDA == nil {
+				match = &compats[i]
+			}
+		case compat.CUDA != nil && !version.Greater(*compat.CUDA, cuda):
+			if match == nil || version.Greater(*compat.CUDA, *match.CUDA) {
+				match = &compats[i]
+			}
+		}
+	}
+	if match != nil && (python == "" || slices.Contains(match.Pythons, python)) {
+		return match, nil
+	}
+
+	requested := "CPU"
+	if cuda != "" {
+		requested = "CUDA " + cuda
+	}
+	if python != "" {
+		requested += " and Python " + python
+	}
+	combinations := []string{}
+	for _, compat := range compats {
+		combination := "CPU"
+		if compat.CUDA != nil {
+			combination = "CUDA " + *compat.CUDA
+		}
+		combinations = append(combinations, fmt.Sprintf("  %s with Python %s", combination, strings.Join(compat.Pythons, ", ")))
+	}
+	return nil, fmt.Errorf("torch==%s isn't compatible with %s. Compatible combinations are:\n%s", torch, requested, strings.Join(combinations, "\n"))
+}
+
+// torchCompatibilitiesFor returns the entries for torch, newest CUDA first. torch can be
+// a minor version, like 2.0.
+func torchCompatibilitiesFor(torch string) []TorchCompatibility {
+	compats := []TorchCompatibility{}
+	for _, matrix := range [][]TorchCompatibility{TorchCompatibilityMatrix, TorchMinorCompatibilityMatrix} {
+		for _, compat := range matrix {
+			if compat.TorchVersion() == torch {
+				compats = append(compats, compat)
+			}
+		}
+		if len(compats) > 0 {
+			break
+		}
+	}
+	sort.SliceStable(compats, func(i, j int) bool {
+		a, b := compats[i].CUDA, compats[j].CUDA
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return version.Greater(*a, *b)
+	})
+	return compats
+}
+
+// nearestTorchVersions returns up to n known torch versions either side of torch
+func nearestTorchVersions(torch string, n int) []string {
+	versions := []string{}
+	for _, compat := range TorchCompatibilityMatrix {
+		if !slices.Contains(versions, compat.TorchVersion()) {
+			versions = append(versions, compat.TorchVersion())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.Greater(versions[i], versions[j])
+	})
+
+	requested, err := version.NewVersion(torch)
+	if err != nil {
+		return versions[:min(n, len(versions))]
+	}
+	// versions are newest first, so find the first one older than torch and take some either side
+	i := sort.Search(len(versions), func(i int) bool {
+		return requested.Greater(version.MustVersion(versions[i]))
+	})
+	start := max(0, min(i-n/2, len(versions)-n))
+	return versions[start:min(start+n, len(versions))]
+}
+
 func torchvisionCPUPackage(ver, goos, goarch string) (name, cpuVersion, findLinks, extraIndexURL string, err error) {
 	for _, compat := range TorchCompatibilityMatrix {
 		if compat.TorchvisionVersion() == ver && compat.CUDA == nil {
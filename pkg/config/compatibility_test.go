@@ -64,3 +64,24 @@ func TestGenerateTorchMinorVersionCompatibilityMatrix(t *testing.T) {
 func stringp(s string) *string {
 	return &s
 }
+
+func TestResolveTorchCompatibility(t *testing.T) {
+	compat, err := ResolveTorchCompatibility("2.0.1", "11.8", "3.10")
+	require.NoError(t, err)
+	require.Equal(t, "https://download.pytorch.org/whl/cu118", compat.ExtraIndexURL)
+	require.Equal(t, "11.8", *compat.CUDA)
+
+	compat, err = ResolveTorchCompatibility("2.0.1", "", "3.10.4")
+	require.NoError(t, err)
+	require.Nil(t, compat.CUDA)
+
+	_, err = ResolveTorchCompatibility("2.0.1", "11.8", "3.6")
+	require.ErrorContains(t, err, "torch==2.0.1 isn't compatible with CUDA 11.8 and Python 3.6. Compatible combinations are:\n  CUDA 11.8 with Python")
+
+	_, err = ResolveTorchCompatibility("2.0.1", "10.2", "3.10")
+	require.ErrorContains(t, err, "torch==2.0.1 isn't compatible with CUDA 10.2 and Python 3.10")
+
+	_, err = ResolveTorchCompatibility("2.0.99", "11.8", "3.10")
+	require.ErrorContains(t, err, "Cog doesn't know about torch==2.0.99. The nearest versions it knows about are: ")
+	require.ErrorContains(t, err, "2.0.1")
+}
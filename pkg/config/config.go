@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -35,24 +37,37 @@ const (
 )
 
 type RunItem struct {
-	Command string `json:"command,omitempty" yaml:"command"`
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
 	Mounts  []struct {
-		Type   string `json:"type,omitempty" yaml:"type"`
-		ID     string `json:"id,omitempty" yaml:"id"`
-		Target string `json:"target,omitempty" yaml:"target"`
-	} `json:"mounts,omitempty" yaml:"mounts"`
+		Type   string `json:"type,omitempty" yaml:"type,omitempty"`
+		ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+		Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	} `json:"mounts,omitempty" yaml:"mounts,omitempty"`
+	// Env is only set while this command runs. It is excluded from JSON so that
+	// build-time values don't end up in the config label on the image.
+	Env map[string]string `json:"-" yaml:"env,omitempty"`
 }
 
 type Build struct {
-	GPU                bool      `json:"gpu,omitempty" yaml:"gpu"`
-	PythonVersion      string    `json:"python_version,omitempty" yaml:"python_version"`
-	PythonRequirements string    `json:"python_requirements,omitempty" yaml:"python_requirements"`
-	PythonPackages     []string  `json:"python_packages,omitempty" yaml:"python_packages"` // Deprecated, but included for backwards compatibility
-	Run                []RunItem `json:"run,omitempty" yaml:"run"`
-	SystemPackages     []string  `json:"system_packages,omitempty" yaml:"system_packages"`
-	PreInstall         []string  `json:"pre_install,omitempty" yaml:"pre_install"` // Deprecated, but included for backwards compatibility
-	CUDA               string    `json:"cuda,omitempty" yaml:"cuda"`
-	CuDNN              string    `json:"cudnn,omitempty" yaml:"cudnn"`
+	GPU                bool     `json:"gpu,omitempty" yaml:"gpu,omitempty"`
+	PythonVersion      string   `json:"python_version,omitempty" yaml:"python_version,omitempty"`
+	PythonRequirements string   `json:"python_requirements,omitempty" yaml:"python_requirements,omitempty"`
+	PythonPackages     []string `json:"python_packages,omitempty" yaml:"python_packages,omitempty"` // Deprecated, but included for backwards compatibility
+	// Package indexes are excluded from JSON so that index URLs, which often have
+	// credentials in them, don't end up in the config label on the image.
+	PythonIndexURL       string    `json:"-" yaml:"python_index_url,omitempty"`
+	PythonExtraIndexURLs []string  `json:"-" yaml:"python_extra_index_urls,omitempty"`
+	PythonTrustedHosts   []string  `json:"-" yaml:"python_trusted_hosts,omitempty"`
+	Run                  []RunItem `json:"run,omitempty" yaml:"run,omitempty"`
+	SystemPackages       []string  `json:"system_packages,omitempty" yaml:"system_packages,omitempty"`
+	BuildSystemPackages  []string  `json:"build_system_packages,omitempty" yaml:"build_system_packages,omitempty"`
+	AptKeys              []string  `json:"apt_keys,omitempty" yaml:"apt_keys,omitempty"`
+	AptRepositories      []string  `json:"apt_repositories,omitempty" yaml:"apt_repositories,omitempty"`
+	PreInstall           []string  `json:"pre_install,omitempty" yaml:"pre_install,omitempty"` // Deprecated, but included for backwards compatibility
+	PostInstall          []string  `json:"post_install,omitempty" yaml:"post_install,omitempty"`
+	RemoveAfter          []string  `json:"remove_after,omitempty" yaml:"remove_after,omitempty"`
+	CUDA                 string    `json:"cuda,omitempty" yaml:"cuda,omitempty"`
+	CuDNN                string    `json:"cudnn,omitempty" yaml:"cudnn,omitempty"`
 
 	pythonRequirementsContent []string
 }
@@ -63,10 +78,12 @@ type Example struct {
 }
 
 type Config struct {
+	// Version is the version of the cog.yaml format the file was written for
+	Version string `json:"cog_yaml_version,omitempty" yaml:"cog_yaml_version,omitempty"`
 	Build   *Build `json:"build" yaml:"build"`
-	Image   string `json:"image,omitempty" yaml:"image"`
-	Predict string `json:"predict,omitempty" yaml:"predict"`
-	Train   string `json:"train,omitempty" yaml:"train"`
+	Image   string `json:"image,omitempty" yaml:"image,omitempty"`
+	Predict string `json:"predict,omitempty" yaml:"predict,omitempty"`
+	Train   string `json:"train,omitempty" yaml:"train,omitempty"`
 }
 
 func DefaultConfig() *Config {
@@ -102,6 +119,7 @@ func (r *RunItem) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				ID     string `yaml:"id"`
 				Target string `yaml:"target"`
 			} `yaml:"mounts,omitempty"`
+			Env map[string]string `yaml:"env,omitempty"`
 		}{}
 
 		if err := yaml.Unmarshal(data, &aux); err != nil {
@@ -133,6 +151,7 @@ func (r *RunItem) UnmarshalJSON(data []byte) error {
 				ID     string `json:"id"`
 				Target string `json:"target"`
 			} `json:"mounts,omitempty"`
+			Env map[string]string `json:"env,omitempty"`
 		}{}
 
 		jsonData, err := json.Marshal(v)
@@ -159,7 +178,7 @@ func FromYAML(contents []byte) (*Config, error) {
 	}
 	// Everything assumes Build is not nil
 	if len(contents) != 0 && config.Build != nil {
-		err := Validate(string(contents), "")
+		err := ValidateVersioned(string(contents), config.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -288,6 +307,26 @@ func (c *Config) ValidateAndComplete(projectDir string) error {
 		}
 	}
 
+	for _, run := range c.Build.Run {
+		if err := validateRunEnv(run.Env); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.validatePythonIndexes(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.validateAptSources(projectDir); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, p := range c.Build.RemoveAfter {
+		if strings.TrimSpace(p) == "" || strings.ContainsAny(p, " \t\n;&|") || path.Clean(p) == "/" {
+			errs = append(errs, fmt.Errorf("Invalid remove_after path %q. It must be a path or glob without spaces, and can't be /", p))
+		}
+	}
+
 	// Backwards compatibility
 	if len(c.Build.PythonPackages) > 0 {
 		c.Build.pythonRequirementsContent = c.Build.PythonPackages
@@ -299,6 +338,10 @@ func (c *Config) ValidateAndComplete(projectDir string) error {
 		}
 	}
 
+	if err := c.validateTorchCompatibility(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -410,6 +453,74 @@ func (c *Config) pythonPackageForArch(pkg, goos, goarch string) (actualPackage s
 	return pkgWithVersion, findLinksList, extraIndexURLs, nil
 }
 
+func (c *Config) validatePythonIndexes() error {
+	indexURLs := c.Build.PythonExtraIndexURLs
+	if c.Build.PythonIndexURL != "" {
+		indexURLs = append([]string{c.Build.PythonIndexURL}, indexURLs...)
+	}
+	for _, indexURL := range indexURLs {
+		u, err := url.Parse(indexURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Invalid Python package index URL %q. It must be an http:// or https:// URL", indexURL)
+		}
+	}
+	for _, host := range c.Build.PythonTrustedHosts {
+		if host == "" || strings.ContainsAny(host, "/ \t\r\n") {
+			return fmt.Errorf("Invalid Python trusted host %q. It must be a hostname, optionally with a port, like pypi.example.com:8080", host)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateAptSources(projectDir string) error {
+	for _, key := range c.Build.AptKeys {
+		if IsAptKeyURL(key) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(projectDir, key)); err != nil {
+			return fmt.Errorf("Invalid apt key %q. It must be an http:// or https:// URL, or a path to a key file in your project: %w", key, err)
+		}
+	}
+	for _, repo := range c.Build.AptRepositories {
+		fields := strings.Fields(repo)
+		// Skip options like [arch=amd64], which can have spaces in them
+		rest := fields
+		if len(fields) > 1 && strings.HasPrefix(fields[1], "[") {
+			rest = nil
+			for i := 1; i < len(fields); i++ {
+				if strings.HasSuffix(fields[i], "]") {
+					rest = append([]string{fields[0]}, fields[i+1:]...)
+					break
+				}
+			}
+		}
+		if len(rest) < 3 || (rest[0] != "deb" && rest[0] != "deb-src") {
+			return fmt.Errorf("Invalid apt repository %q. It must be a sources.list line like 'deb https://example.com/apt stable main'", repo)
+		}
+		if len(c.Build.AptKeys) == 0 && !strings.Contains(repo, "signed-by=") {
+			return fmt.Errorf("The apt repository %q isn't signed by any key. Add its GPG key to apt_keys in your cog.yaml", repo)
+		}
+	}
+	return nil
+}
+
+// IsAptKeyURL returns true if an apt_keys entry should be downloaded rather than read from the project
+func IsAptKeyURL(key string) bool {
+	return strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://")
+}
+
+// envNameRegexp matches the environment variable names that can be set for a run command
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func validateRunEnv(env map[string]string) error {
+	for name := range env {
+		if !envNameRegexp.MatchString(name) {
+			return fmt.Errorf("Invalid environment variable name %q in 'run'. Names must only contain letters, numbers, and underscores, and must not start with a number", name)
+		}
+	}
+	return nil
+}
+
 func ValidateCudaVersion(cudaVersion string) error {
 	parts := strings.Split(cudaVersion, ".")
 	if len(parts) < 2 {
@@ -524,6 +635,45 @@ Compatible cuDNN version is: %s`, c.Build.CuDNN, tfVersion, tfCuDNN)
 	return nil
 }
 
+// validateTorchCompatibility fails if Cog knows the pinned torch version won't install with
+// the CUDA version, so it fails now instead of partway through the build. An unlisted Python
+// version is only a warning, because the Python versions in the compatibility matrix can lag
+// behind the wheels that have been published. Versions Cog doesn't know about are left to pip.
+func (c *Config) validateTorchCompatibility() error {
+	torchVersion, ok := c.TorchVersion()
+	if !ok {
+		return nil
+	}
+	compats := torchCompatibilitiesFor(torchVersion)
+	hasCPU := false
+	for _, compat := range compats {
+		if compat.CUDA == nil {
+			hasCPU = true
+		}
+	}
+	cuda := ""
+	if c.Build.GPU {
+		cuda = c.Build.CUDA
+	}
+	switch {
+	case len(compats) == 0:
+		return nil
+	case c.Build.GPU && cuda == "":
+		// validateAndCompleteCUDA has already failed
+		return nil
+	case !c.Build.GPU && !hasCPU:
+		// No CPU build is known, so the default package is installed
+		return nil
+	}
+	if _, err := ResolveTorchCompatibility(torchVersion, cuda, ""); err != nil {
+		return err
+	}
+	if _, err := ResolveTorchCompatibility(torchVersion, cuda, c.Build.PythonVersion); err != nil {
+		console.Warnf("%s", err)
+	}
+	return nil
+}
+
 // splitPythonPackage returns the name, version, findLinks, and extraIndexURLs from a requirements.txt line
 // in the form name==version [--find-links=<findLink>] [-f <findLink>] [--extra-index-url=<extraIndexURL>]
 func splitPinnedPythonRequirement(requirement string) (name string, version string, findLinks []string, extraIndexURLs []string, err error) {
@@ -347,6 +347,34 @@ func TestUnsupportedTorch(t *testing.T) {
 	require.Equal(t, "8", config.Build.CuDNN)
 }
 
+func TestIncompatibleTorchFailsValidation(t *testing.T) {
+	config := &Config{
+		Build: &Build{
+			GPU:           true,
+			CUDA:          "10.2",
+			PythonVersion: "3.10",
+			PythonPackages: []string{
+				"torch==2.0.1",
+			},
+		},
+	}
+	err := config.ValidateAndComplete("")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "torch==2.0.1 isn't compatible with CUDA 10.2.")
+
+	// The Python versions in the matrix can be out of date, so they don't fail the build
+	config = &Config{
+		Build: &Build{
+			GPU:           true,
+			PythonVersion: "3.12",
+			PythonPackages: []string{
+				"torch==2.0.1",
+			},
+		},
+	}
+	require.NoError(t, config.ValidateAndComplete(""))
+}
+
 func TestUnsupportedTensorflow(t *testing.T) {
 	// Ensure version is not known by Cog
 	cuda, cudnn, err := cudaFromTF("0.4.1")
@@ -694,3 +722,138 @@ func TestSplitPinnedPythonRequirement(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildRunItemEnvYAML(t *testing.T) {
+	config, err := FromYAML([]byte(`
+build:
+  run:
+  - command: "make install"
+    env:
+      PREFIX: /opt/app
+`))
+	require.NoError(t, err)
+	require.Len(t, config.Build.Run, 1)
+	require.Equal(t, map[string]string{"PREFIX": "/opt/app"}, config.Build.Run[0].Env)
+}
+
+func TestBuildRunItemInvalidEnvName(t *testing.T) {
+	config, err := FromYAML([]byte(`
+build:
+  run:
+  - command: "make install"
+    env:
+      1PREFIX: /opt/app
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete("")
+	require.ErrorContains(t, err, `Invalid environment variable name "1PREFIX"`)
+}
+
+func TestValidatePythonIndexes(t *testing.T) {
+	config, err := FromYAML([]byte(`
+build:
+  python_index_url: https://pypi.example.com/simple
+  python_extra_index_urls:
+    - http://wheels.example.com/simple
+  python_trusted_hosts:
+    - wheels.example.com:8080
+`))
+	require.NoError(t, err)
+	require.NoError(t, config.ValidateAndComplete(""))
+
+	// They're left out of the config label on the image, because they can have credentials in them
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "example.com")
+
+	config, err = FromYAML([]byte(`
+build:
+  python_index_url: pypi.example.com/simple
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete("")
+	require.ErrorContains(t, err, `Invalid Python package index URL "pypi.example.com/simple"`)
+
+	config, err = FromYAML([]byte(`
+build:
+  python_trusted_hosts:
+    - https://pypi.example.com
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete("")
+	require.ErrorContains(t, err, `Invalid Python trusted host "https://pypi.example.com"`)
+}
+
+func TestValidateAptSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := os.WriteFile(path.Join(tmpDir, "example.gpg"), []byte("key"), 0o644)
+	require.NoError(t, err)
+
+	config, err := FromYAML([]byte(`
+build:
+  apt_keys:
+    - https://example.com/apt/key.asc
+    - example.gpg
+  apt_repositories:
+    - deb https://example.com/apt stable main
+`))
+	require.NoError(t, err)
+	require.NoError(t, config.ValidateAndComplete(tmpDir))
+
+	config, err = FromYAML([]byte(`
+build:
+  apt_keys:
+    - missing.gpg
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete(tmpDir)
+	require.ErrorContains(t, err, `Invalid apt key "missing.gpg"`)
+
+	config, err = FromYAML([]byte(`
+build:
+  apt_keys:
+    - example.gpg
+  apt_repositories:
+    - https://example.com/apt stable main
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete(tmpDir)
+	require.ErrorContains(t, err, `Invalid apt repository "https://example.com/apt stable main"`)
+
+	config, err = FromYAML([]byte(`
+build:
+  apt_repositories:
+    - deb https://example.com/apt stable main
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete(tmpDir)
+	require.ErrorContains(t, err, "isn't signed by any key")
+
+	config, err = FromYAML([]byte(`
+build:
+  apt_keys:
+    - example.gpg
+  apt_repositories:
+    - deb [arch=amd64 https://example.com/apt stable main
+`))
+	require.NoError(t, err)
+	err = config.ValidateAndComplete(tmpDir)
+	require.ErrorContains(t, err, "Invalid apt repository")
+}
+
+func TestValidateRemoveAfter(t *testing.T) {
+	config, err := FromYAML([]byte(`
+build:
+  remove_after:
+    - /usr/share/doc
+    - build/*.o
+`))
+	require.NoError(t, err)
+	require.NoError(t, config.ValidateAndComplete(""))
+
+	for _, p := range []string{"/", "/usr/share/doc; curl example.com", ""} {
+		config := &Config{Build: &Build{RemoveAfter: []string{p}}}
+		err := config.ValidateAndComplete("")
+		require.ErrorContains(t, err, "Invalid remove_after path", p)
+	}
+}
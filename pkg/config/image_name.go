@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"os"
 	"path"
 	"regexp"
 	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
 )
 
 // DockerImageName returns the default Docker image name for images
@@ -35,3 +39,22 @@ func DockerImageName(projectDir string) string {
 func BaseDockerImageName(projectDir string) string {
 	return DockerImageName(projectDir) + "-base"
 }
+
+// ImageName returns the name to build or push the image as. A name passed on the command line
+// overrides the image option in cog.yaml, which can refer to environment variables, like
+// r8.im/$REPLICATE_USER/model. It returns an empty string if neither is set.
+func (c *Config) ImageName(override string) (string, error) {
+	imageName := override
+	source := "image name"
+	if imageName == "" {
+		imageName = os.ExpandEnv(c.Image)
+		source = "'image' option in cog.yaml"
+	}
+	if imageName == "" {
+		return "", nil
+	}
+	if _, err := name.ParseReference(imageName); err != nil {
+		return "", fmt.Errorf("Invalid %s %q: %w", source, imageName, err)
+	}
+	return imageName, nil
+}
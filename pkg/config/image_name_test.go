@@ -13,3 +13,27 @@ func TestDockerImageName(t *testing.T) {
 	require.Equal(t, "cog-my-great-model", DockerImageName("/home/joe/my great model"))
 	require.Equal(t, 30, len(DockerImageName("/home/joe/verylongverylongverylongverylongverylongverylongverylong")))
 }
+
+func TestImageName(t *testing.T) {
+	t.Setenv("COG_TEST_USER", "alice")
+	config := &Config{Image: "r8.im/$COG_TEST_USER/model"}
+
+	imageName, err := config.ImageName("")
+	require.NoError(t, err)
+	require.Equal(t, "r8.im/alice/model", imageName)
+
+	// The command line takes precedence over cog.yaml
+	imageName, err = config.ImageName("r8.im/bob/model:v2")
+	require.NoError(t, err)
+	require.Equal(t, "r8.im/bob/model:v2", imageName)
+
+	imageName, err = (&Config{}).ImageName("")
+	require.NoError(t, err)
+	require.Equal(t, "", imageName)
+
+	_, err = config.ImageName("r8.im/Bob/Model")
+	require.ErrorContains(t, err, `Invalid image name "r8.im/Bob/Model"`)
+
+	_, err = (&Config{Image: "r8.im/user/model:bad tag"}).ImageName("")
+	require.ErrorContains(t, err, `Invalid 'image' option in cog.yaml "r8.im/user/model:bad tag"`)
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Marshal returns the config serialized as "json" or "yaml". Call it after
+// ValidateAndComplete to get the resolved config, including defaults and the
+// CUDA and cuDNN versions chosen for the Python packages.
+func (c *Config) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml":
+		return yaml.Marshal(c.withoutJSONExcludedFields())
+	default:
+		return nil, fmt.Errorf("Unknown format %q, must be one of: json, yaml", format)
+	}
+}
+
+// withoutJSONExcludedFields returns a copy of c without the fields that are left out of
+// JSON, like run item env and package index URLs, so that both formats show the same config
+func (c *Config) withoutJSONExcludedFields() *Config {
+	copied := *c
+	if c.Build == nil {
+		return &copied
+	}
+	build := *c.Build
+	build.PythonIndexURL = ""
+	build.PythonExtraIndexURLs = nil
+	build.PythonTrustedHosts = nil
+	build.Run = make([]RunItem, len(c.Build.Run))
+	for i, run := range c.Build.Run {
+		run.Env = nil
+		build.Run[i] = run
+	}
+	copied.Build = &build
+	return &copied
+}
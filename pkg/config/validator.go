@@ -4,10 +4,14 @@ import (
 	// blank import for embeds
 	_ "embed"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/xeipuuv/gojsonschema"
 	"sigs.k8s.io/yaml"
+
+	"github.com/replicate/cog/pkg/util/console"
+	"github.com/replicate/cog/pkg/util/version"
 )
 
 const (
@@ -64,6 +68,62 @@ func Validate(yamlConfig string, version string) error {
 	return ValidateSchema(schemaLoader, dataLoader)
 }
 
+// ValidateVersioned validates cog.yaml contents that declare cog_yaml_version. If that's newer
+// than this version of Cog understands, options it doesn't recognize are listed in a warning
+// rather than failing validation, so the rest of the file can still be used.
+func ValidateVersioned(yamlConfig string, declaredVersion string) error {
+	if declaredVersion == "" {
+		return Validate(yamlConfig, "")
+	}
+	declared, err := version.NewVersion(declaredVersion)
+	if err != nil {
+		return fmt.Errorf("Invalid cog_yaml_version %q in cog.yaml: %w", declaredVersion, err)
+	}
+	if !declared.Greater(version.MustVersion(defaultVersion)) {
+		return Validate(yamlConfig, declaredVersion)
+	}
+
+	j, err := yaml.YAMLToJSON([]byte(yamlConfig))
+	if err != nil {
+		return err
+	}
+	schemaLoader, err := getSchema(defaultVersion)
+	if err != nil {
+		return err
+	}
+	result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewStringLoader(string(j)))
+	if err != nil {
+		return err
+	}
+
+	unknown, otherErrors := splitUnknownOptions(result.Errors())
+	if len(otherErrors) > 0 {
+		return getMostSpecificError(otherErrors)
+	}
+	if len(unknown) > 0 {
+		console.Warnf("cog.yaml is for cog_yaml_version %s, but this version of Cog only understands %s. These options will be ignored: %s. Upgrade Cog to use them.", declaredVersion, defaultVersion, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// splitUnknownOptions separates errors for options that aren't in the schema, returning
+// their names like build.some_option, from all other errors.
+func splitUnknownOptions(errors []gojsonschema.ResultError) (unknown []string, other []gojsonschema.ResultError) {
+	for _, err := range errors {
+		if err.Type() != "additional_property_not_allowed" {
+			other = append(other, err)
+			continue
+		}
+		option := fmt.Sprintf("%v", err.Details()["property"])
+		if err.Field() != gojsonschema.STRING_CONTEXT_ROOT {
+			option = err.Field() + "." + option
+		}
+		unknown = append(unknown, option)
+	}
+	sort.Strings(unknown)
+	return unknown, other
+}
+
 func ValidateSchema(schemaLoader, dataLoader gojsonschema.JSONLoader) error {
 	result, err := gojsonschema.Validate(schemaLoader, dataLoader)
 	if err != nil {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/yaml"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -91,3 +93,51 @@ func TestValidateNullListsAllowed(t *testing.T) {
 	err := Validate(config, "1.0")
 	require.NoError(t, err)
 }
+
+func TestValidateVersionedNewerVersionAllowsUnknownOptions(t *testing.T) {
+	config := `cog_yaml_version: "1.1"
+build:
+  python_version: "3.11"
+  some_new_option: true
+some_new_section: {}`
+
+	require.NoError(t, ValidateVersioned(config, "1.1"))
+
+	// Other problems are still errors
+	config = `cog_yaml_version: "1.1"
+build:
+  gpu: "yes please"
+  some_new_option: true`
+	err := ValidateVersioned(config, "1.1")
+	require.ErrorContains(t, err, "must be a boolean")
+}
+
+func TestValidateVersionedCurrentVersionRejectsUnknownOptions(t *testing.T) {
+	config := `cog_yaml_version: "1.0"
+build:
+  some_new_option: true`
+
+	err := ValidateVersioned(config, "1.0")
+	require.ErrorContains(t, err, "Additional property some_new_option is not allowed")
+
+	err = ValidateVersioned(config, "one")
+	require.ErrorContains(t, err, `Invalid cog_yaml_version "one"`)
+}
+
+func TestSplitUnknownOptions(t *testing.T) {
+	config := `build:
+  python_version: "3.11"
+  some_new_option: true
+some_new_section: {}`
+
+	j, err := yaml.YAMLToJSON([]byte(config))
+	require.NoError(t, err)
+	schemaLoader, err := getSchema(defaultVersion)
+	require.NoError(t, err)
+	result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewStringLoader(string(j)))
+	require.NoError(t, err)
+
+	unknown, other := splitUnknownOptions(result.Errors())
+	require.Equal(t, []string{"build.some_new_option", "some_new_section"}, unknown)
+	require.Empty(t, other)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/replicate/cog/pkg/config"
+	"github.com/replicate/cog/pkg/global"
 
 	"github.com/replicate/cog/pkg/util"
 	"github.com/replicate/cog/pkg/util/console"
@@ -54,10 +55,18 @@ func Build(dir, dockerfile, imageName string, secrets []string, noCache bool, pr
 		args = append(args, "--cache-to", "type=inline")
 	}
 
+	if global.Quiet {
+		progressOutput = "quiet"
+	}
+	progress, err := buildxProgressOutput(progressOutput)
+	if err != nil {
+		return err
+	}
+
 	args = append(args,
 		"--file", "-",
 		"--tag", imageName,
-		"--progress", progressOutput,
+		"--progress", progress,
 		".",
 	)
 
@@ -71,6 +80,26 @@ func Build(dir, dockerfile, imageName string, secrets []string, noCache bool, pr
 	return cmd.Run()
 }
 
+// buildxProgressOutputs maps the progress output types cog accepts to the values of buildx --progress.
+// "json" emits the raw BuildKit solve status events, one JSON object per line.
+var buildxProgressOutputs = map[string]string{
+	"auto":  "auto",
+	"tty":   "tty",
+	"plain": "plain",
+	"json":  "rawjson",
+	"quiet": "quiet",
+	// Accepted so that BUILDKIT_PROGRESS=rawjson works as a default
+	"rawjson": "rawjson",
+}
+
+func buildxProgressOutput(progressOutput string) (string, error) {
+	progress, ok := buildxProgressOutputs[progressOutput]
+	if !ok {
+		return "", fmt.Errorf("Invalid progress output %q, must be one of: auto, tty, plain, json", progressOutput)
+	}
+	return progress, nil
+}
+
 func BuildAddLabelsAndSchemaToImage(image string, labels map[string]string, bundledSchemaFile string, bundledSchemaPy string) error {
 	var args []string
 
@@ -0,0 +1,25 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildxProgressOutput(t *testing.T) {
+	for progressOutput, expected := range map[string]string{
+		"auto":    "auto",
+		"tty":     "tty",
+		"plain":   "plain",
+		"json":    "rawjson",
+		"rawjson": "rawjson",
+		"quiet":   "quiet",
+	} {
+		actual, err := buildxProgressOutput(progressOutput)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	_, err := buildxProgressOutput("fancy")
+	require.ErrorContains(t, err, `Invalid progress output "fancy"`)
+}
@@ -5,11 +5,16 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/replicate/cog/pkg/global"
 	"github.com/replicate/cog/pkg/util/console"
 )
 
 func Pull(image string) error {
-	cmd := exec.Command("docker", "pull", image)
+	args := []string{"pull"}
+	if global.Quiet {
+		args = append(args, "--quiet")
+	}
+	cmd := exec.Command("docker", append(args, image)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -5,13 +5,17 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/replicate/cog/pkg/global"
 	"github.com/replicate/cog/pkg/util/console"
 )
 
 func Push(image string) error {
-	cmd := exec.Command(
-		"docker", "push", image)
-	cmd.Stdout = os.Stdout
+	args := []string{"push"}
+	if global.Quiet {
+		args = append(args, "--quiet")
+	}
+	cmd := exec.Command("docker", append(args, image)...)
+	cmd.Stdout = os.Stderr // redirect stdout to stderr - push output is all messaging
 	cmd.Stderr = os.Stderr
 
 	console.Debug("$ " + strings.Join(cmd.Args, " "))
@@ -0,0 +1,29 @@
+package docker
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+// Save writes image to a tarball at path, in the format produced by `docker save`
+func Save(image string, path string) error {
+	cmd := exec.Command("docker", "image", "save", "--output", path, image)
+	cmd.Env = os.Environ()
+	cmd.Stderr = os.Stderr
+
+	console.Debug("$ " + strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
+
+// Load loads an image from a tarball at path, as written by Save
+func Load(path string) error {
+	cmd := exec.Command("docker", "image", "load", "--input", path)
+	cmd.Env = os.Environ()
+	cmd.Stderr = os.Stderr
+
+	console.Debug("$ " + strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
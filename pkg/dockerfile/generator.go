@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -66,6 +67,10 @@ type Generator struct {
 	modelFiles []string
 
 	pythonRequirementsContents string
+
+	// absolute path to the pip config for the package indexes in cog.yaml, which is
+	// outside Dir so it isn't copied into the image
+	pipConfigPath string
 }
 
 func NewGenerator(config *config.Config, dir string) (*Generator, error) {
@@ -180,9 +185,15 @@ func (g *Generator) GenerateDockerfileWithoutSeparateWeights() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	postInstallCommands, err := g.postInstallCommands()
+	if err != nil {
+		return "", err
+	}
 	return joinStringsWithoutLineSpace([]string{
 		base,
 		`COPY . /src`,
+		postInstallCommands,
+		g.removeAfterCommand(),
 	}), nil
 }
 
@@ -207,11 +218,18 @@ func (g *Generator) GenerateModelBaseWithSeparateWeights(imageName string) (weig
 		base = append(base, "", fmt.Sprintf("COPY --from=%s --link %[2]s %[2]s", "weights", path.Join("/src", p)))
 	}
 
+	postInstallCommands, err := g.postInstallCommands()
+	if err != nil {
+		return "", "", "", err
+	}
+
 	base = append(base,
 		`WORKDIR /src`,
 		`EXPOSE 5000`,
 		`CMD ["python", "-m", "cog.server.http"]`,
 		`COPY . /src`,
+		postInstallCommands,
+		g.removeAfterCommand(),
 	)
 
 	dockerignoreContents = makeDockerignoreForWeights(g.modelDirs, g.modelFiles)
@@ -249,9 +267,23 @@ func (g *Generator) Cleanup() error {
 	if err := os.RemoveAll(g.tmpDir); err != nil {
 		return fmt.Errorf("Failed to clean up %s: %w", g.tmpDir, err)
 	}
+	if g.pipConfigPath != "" {
+		if err := os.Remove(g.pipConfigPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Failed to clean up %s: %w", g.pipConfigPath, err)
+		}
+	}
 	return nil
 }
 
+// Secrets returns the build secrets the generated Dockerfile needs, in the form
+// 'id=foo,src=/path/to/file'. Call it after generating the Dockerfile.
+func (g *Generator) Secrets() []string {
+	if g.pipConfigPath == "" {
+		return []string{}
+	}
+	return []string{"id=" + pipConfigSecretID + ",src=" + g.pipConfigPath}
+}
+
 func (g *Generator) BaseImage() (string, error) {
 	if g.useCogBaseImage {
 		var changed bool
@@ -321,21 +353,86 @@ chmod +x /sbin/tini`,
 
 func (g *Generator) aptInstalls() (string, error) {
 	packages := g.Config.Build.SystemPackages
-	if len(packages) == 0 {
-		return "", nil
-	}
 
 	if g.useCogBaseImage {
+		// Python packages are built in the final image, so it needs their build packages too
+		packages = append([]string{}, packages...)
+		for _, pkg := range g.Config.Build.BuildSystemPackages {
+			if !slices.ContainsString(packages, pkg) {
+				packages = append(packages, pkg)
+			}
+		}
 		packages = slices.FilterString(packages, func(pkg string) bool {
 			return !slices.ContainsString(baseImageSystemPackages, pkg)
 		})
 	}
+	if len(packages) == 0 {
+		return "", nil
+	}
 
+	install := "RUN --mount=type=cache,target=/var/cache/apt,sharing=locked apt-get update -qq && apt-get install -qqy " +
+		strings.Join(packages, " ") +
+		" && rm -rf /var/lib/apt/lists/*"
+	return joinStringsWithoutLineSpace([]string{g.aptSources(), install}), nil
+}
+
+// buildStageAptInstalls installs build_system_packages in the deps stage, before Python
+// packages are built. The deps stage is Debian rather than the final image's Ubuntu, so it
+// doesn't get system_packages or apt_repositories, which are only meant for the final image.
+func (g *Generator) buildStageAptInstalls() string {
+	packages := g.Config.Build.BuildSystemPackages
+	if len(packages) == 0 {
+		return ""
+	}
 	return "RUN --mount=type=cache,target=/var/cache/apt,sharing=locked apt-get update -qq && apt-get install -qqy " +
 		strings.Join(packages, " ") +
-		" && rm -rf /var/lib/apt/lists/*", nil
+		" && rm -rf /var/lib/apt/lists/*"
+}
+
+// aptSources installs the keys in apt_keys and adds the repositories in apt_repositories,
+// signed by those keys, so that system packages can be installed from them
+func (g *Generator) aptSources() string {
+	keyrings := []string{}
+	lines := []string{}
+	for i, key := range g.Config.Build.AptKeys {
+		// apt reads ASCII-armored keys from .asc files and binary keys from .gpg files
+		ext := ".asc"
+		if strings.HasSuffix(key, ".gpg") {
+			ext = ".gpg"
+		}
+		keyring := fmt.Sprintf("%s/cog-%d%s", aptKeyringsDir, i, ext)
+		keyrings = append(keyrings, keyring)
+		lines = append(lines, fmt.Sprintf("ADD --chmod=644 %s %s", key, keyring))
+	}
+	if len(g.Config.Build.AptRepositories) == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	sources := []string{}
+	for _, repo := range g.Config.Build.AptRepositories {
+		fields := strings.Fields(repo)
+		if len(keyrings) > 0 && !strings.Contains(repo, "signed-by=") {
+			signedBy := "signed-by=" + strings.Join(keyrings, ",")
+			if strings.HasPrefix(fields[1], "[") {
+				// Add it to the options that are already there, like [arch=amd64]
+				for i := 1; i < len(fields); i++ {
+					if strings.HasSuffix(fields[i], "]") {
+						fields[i] = strings.TrimSpace(strings.TrimSuffix(fields[i], "]")+" "+signedBy) + "]"
+						break
+					}
+				}
+			} else {
+				fields = append([]string{fields[0], "[" + signedBy + "]"}, fields[1:]...)
+			}
+		}
+		sources = append(sources, strings.ReplaceAll(strings.Join(fields, " "), "'", `'"'"'`))
+	}
+	lines = append(lines, "RUN printf '%s\\n' '"+strings.Join(sources, "' '")+"' > /etc/apt/sources.list.d/cog.list")
+	return strings.Join(lines, "\n")
 }
 
+const aptKeyringsDir = "/etc/apt/keyrings"
+
 func (g *Generator) installPython() (string, error) {
 	if g.Config.Build.GPU && g.useCudaBaseImage && !g.useCogBaseImage {
 		return g.installPythonCUDA()
@@ -413,12 +510,52 @@ func (g *Generator) pipInstalls() (string, error) {
 		return "", err
 	}
 
+	pipConfig, err := g.pipConfig()
+	if err != nil {
+		return "", err
+	}
 	return strings.Join([]string{
 		copyLine[0],
-		"RUN pip install -r " + containerPath,
+		"RUN " + pipConfig + "pip install -r " + containerPath,
 	}, "\n"), nil
 }
 
+// pipConfigSecretID is the ID of the build secret with the pip config for the package
+// indexes in cog.yaml
+const pipConfigSecretID = "cog-pip-conf"
+
+// pipConfig returns the start of a RUN instruction that gives pip install the package
+// indexes in cog.yaml. Index URLs often have credentials in them, so they're passed in a
+// build secret rather than as flags or ENV, which would end up in the image's history.
+func (g *Generator) pipConfig() (string, error) {
+	build := g.Config.Build
+	if build.PythonIndexURL == "" && len(build.PythonExtraIndexURLs) == 0 && len(build.PythonTrustedHosts) == 0 {
+		return "", nil
+	}
+	if g.pipConfigPath == "" {
+		lines := []string{"[global]"}
+		if build.PythonIndexURL != "" {
+			lines = append(lines, "index-url = "+build.PythonIndexURL)
+		}
+		if len(build.PythonExtraIndexURLs) > 0 {
+			lines = append(lines, "extra-index-url = "+strings.Join(build.PythonExtraIndexURLs, " "))
+		}
+		if len(build.PythonTrustedHosts) > 0 {
+			lines = append(lines, "trusted-host = "+strings.Join(build.PythonTrustedHosts, " "))
+		}
+		f, err := os.CreateTemp("", "cog-pip-*.conf")
+		if err != nil {
+			return "", fmt.Errorf("Failed to write pip config: %w", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+			return "", fmt.Errorf("Failed to write pip config: %w", err)
+		}
+		g.pipConfigPath = f.Name()
+	}
+	return "--mount=type=secret,id=" + pipConfigSecretID + " PIP_CONFIG_FILE=/run/secrets/" + pipConfigSecretID + " ", nil
+}
+
 func (g *Generator) pipInstallStage() (string, error) {
 	installCog, err := g.installCog()
 	if err != nil {
@@ -451,13 +588,18 @@ func (g *Generator) pipInstallStage() (string, error) {
 	if buildStageDeps != "" {
 		fromLine = fromLine + "\nRUN " + buildStageDeps
 	}
+	pipConfig, err := g.pipConfig()
+	if err != nil {
+		return "", err
+	}
 	lines := []string{
 		fromLine,
+		g.buildStageAptInstalls(),
 		installCog,
 		copyLine[0],
-		"RUN --mount=type=cache,target=/root/.cache/pip pip install -t /dep -r " + containerPath,
+		"RUN --mount=type=cache,target=/root/.cache/pip " + pipConfig + "pip install -t /dep -r " + containerPath,
 	}
-	return strings.Join(lines, "\n"), nil
+	return joinStringsWithoutLineSpace(lines), nil
 }
 
 // copyPipPackagesFromInstallStage copies the Python dependencies installed in the deps stage into the main image
@@ -489,13 +631,31 @@ func (g *Generator) runCommands() (string, error) {
 		runCommands = append(runCommands, config.RunItem{Command: command})
 	}
 
+	return runItemCommands("run", runCommands)
+}
+
+// postInstallCommands runs after dependencies are installed and the source has been copied
+// into the image, so it must come after `COPY . /src`.
+func (g *Generator) postInstallCommands() (string, error) {
+	runItems := []config.RunItem{}
+	for _, command := range g.Config.Build.PostInstall {
+		runItems = append(runItems, config.RunItem{Command: command})
+	}
+	return runItemCommands("post_install", runItems)
+}
+
+func runItemCommands(key string, runItems []config.RunItem) (string, error) {
 	lines := []string{}
-	for _, run := range runCommands {
+	for _, run := range runItems {
 		command := strings.TrimSpace(run.Command)
 		if strings.Contains(command, "\n") {
-			return "", fmt.Errorf(`One of the commands in 'run' contains a new line, which won't work. You need to create a new list item in YAML prefixed with '-' for each command.
+			return "", fmt.Errorf(`One of the commands in '%s' contains a new line, which won't work. You need to create a new list item in YAML prefixed with '-' for each command.
 
-This is the offending line: %s`, command)
+This is the offending line: %s`, key, command)
+		}
+
+		if len(run.Env) > 0 {
+			command = runEnvPrefix(run.Env) + command
 		}
 
 		if len(run.Mounts) > 0 {
@@ -514,6 +674,32 @@ This is the offending line: %s`, command)
 	return strings.Join(lines, "\n"), nil
 }
 
+// runEnvPrefix returns a shell prefix that exports env for a single RUN command.
+// Unlike ENV, the variables don't persist into later layers or the final image.
+func runEnvPrefix(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	assignments := []string{}
+	for _, name := range names {
+		value := strings.ReplaceAll(env[name], "'", `'"'"'`)
+		assignments = append(assignments, fmt.Sprintf("%s='%s'", name, value))
+	}
+	return "export " + strings.Join(assignments, " ") + " && "
+}
+
+// removeAfterCommand deletes the paths in remove_after once everything else is in the image.
+// The paths are left unquoted so that globs are expanded by the shell.
+func (g *Generator) removeAfterCommand() string {
+	if len(g.Config.Build.RemoveAfter) == 0 {
+		return ""
+	}
+	return "RUN rm -rf " + strings.Join(g.Config.Build.RemoveAfter, " ")
+}
+
 // writeTemp writes a temporary file that can be used as part of the build process
 // It returns the lines to add to Dockerfile to make it available and the filename it ends up as inside the container
 func (g *Generator) writeTemp(filename string, contents []byte) ([]string, string, error) {
@@ -547,8 +733,7 @@ func filterEmpty(list []string) []string {
 }
 
 func (g *Generator) GenerateWeightsManifest() (*weights.Manifest, error) {
-	m := weights.NewManifest()
-
+	paths := []string{}
 	for _, dir := range g.modelDirs {
 		err := g.fileWalker(dir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -557,21 +742,19 @@ func (g *Generator) GenerateWeightsManifest() (*weights.Manifest, error) {
 			if info.IsDir() {
 				return nil
 			}
-
-			return m.AddFile(path)
+			paths = append(paths, path)
+			return nil
 		})
 		if err != nil {
 			return nil, err
 		}
 	}
+	paths = append(paths, g.modelFiles...)
 
-	for _, path := range g.modelFiles {
-		err := m.AddFile(path)
-		if err != nil {
-			return nil, err
-		}
+	m := weights.NewManifest()
+	if err := m.AddFiles(paths, runtime.NumCPU()); err != nil {
+		return nil, err
 	}
-
 	return m, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -191,11 +192,12 @@ func TestGenerateFullGPU(t *testing.T) {
 	conf, err := config.FromYAML([]byte(`
 build:
   gpu: true
+  cuda: "11.8"
   system_packages:
     - ffmpeg
     - cowsay
   python_packages:
-    - torch==2.0.1
+    - torch==2.3.0
     - pandas==2.0.3
   run:
     - "cowsay moo"
@@ -236,7 +238,7 @@ COPY . /src`
 	requirements, err := os.ReadFile(path.Join(gen.tmpDir, "requirements.txt"))
 	require.NoError(t, err)
 	require.Equal(t, `--extra-index-url https://download.pytorch.org/whl/cu118
-torch==2.0.1
+torch==2.3.0
 pandas==2.0.3`, string(requirements))
 }
 
@@ -329,11 +331,12 @@ func TestGenerateWithLargeModels(t *testing.T) {
 	conf, err := config.FromYAML([]byte(`
 build:
   gpu: true
+  cuda: "11.8"
   system_packages:
     - ffmpeg
     - cowsay
   python_packages:
-    - torch==2.0.1
+    - torch==2.3.0
     - pandas==2.0.3
   run:
     - "cowsay moo"
@@ -395,7 +398,7 @@ COPY . /src`
 	requirements, err := os.ReadFile(path.Join(gen.tmpDir, "requirements.txt"))
 	require.NoError(t, err)
 	require.Equal(t, `--extra-index-url https://download.pytorch.org/whl/cu118
-torch==2.0.1
+torch==2.3.0
 pandas==2.0.3`, string(requirements))
 
 	expected = `# generated by replicate/cog
@@ -579,3 +582,270 @@ COPY . /src`
 		require.Equal(t, "pandas==2.0.3", string(requirements))
 	}
 }
+
+func TestGenerateRunWithEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  run:
+    - command: make install
+      env:
+        PREFIX: /opt/app
+        CFLAGS: "-O2 -DNAME='cog'"
+    - command: echo $PREFIX
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+	_, actual, _, err := gen.GenerateModelBaseWithSeparateWeights("r8.im/replicate/cog-test")
+	require.NoError(t, err)
+
+	require.Contains(t, actual, "\nRUN export CFLAGS='-O2 -DNAME='\"'\"'cog'\"'\"'' PREFIX='/opt/app' && make install\n")
+	require.Contains(t, actual, "\nRUN echo $PREFIX\n")
+	require.NotContains(t, actual, "ENV PREFIX")
+	require.NotContains(t, actual, "ENV CFLAGS")
+}
+
+func TestGenerateSystemPackagesBeforePythonPackages(t *testing.T) {
+	for _, useCogBaseImage := range []bool{false, true} {
+		t.Run(fmt.Sprintf("useCogBaseImage=%t", useCogBaseImage), func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			// python_packages comes first in the file, but system packages must still be installed first
+			conf, err := config.FromYAML([]byte(`
+build:
+  gpu: true
+  cuda: "11.8"
+  python_packages:
+    - torch==2.3.0
+    - psycopg2==2.9.9
+  system_packages:
+    - libpq5
+  build_system_packages:
+    - libpq-dev
+predict: predict.py:Predictor
+`))
+			require.NoError(t, err)
+			require.NoError(t, conf.ValidateAndComplete(""))
+
+			gen, err := NewGenerator(conf, tmpDir)
+			require.NoError(t, err)
+			gen.SetUseCogBaseImage(useCogBaseImage)
+			_, actual, _, err := gen.GenerateModelBaseWithSeparateWeights("r8.im/replicate/cog-test")
+			require.NoError(t, err)
+
+			if useCogBaseImage {
+				// Python packages are installed in the final image, after all the system packages
+				aptIndex := strings.Index(actual, "apt-get install -qqy libpq5 libpq-dev")
+				pipIndex := strings.Index(actual, "pip install -r /tmp/requirements.txt")
+				require.NotEqual(t, -1, aptIndex)
+				require.NotEqual(t, -1, pipIndex)
+				require.Less(t, aptIndex, pipIndex)
+				return
+			}
+
+			// Only the build packages are installed in the deps stage, before Python packages are built
+			depsStage, finalStage, ok := strings.Cut(actual, "FROM nvidia/cuda")
+			require.True(t, ok)
+			require.NotContains(t, depsStage, "libpq5")
+			buildAptIndex := strings.Index(depsStage, "apt-get install -qqy libpq-dev")
+			pipIndex := strings.Index(depsStage, "-r /tmp/requirements.txt")
+			require.NotEqual(t, -1, buildAptIndex)
+			require.NotEqual(t, -1, pipIndex)
+			require.Less(t, buildAptIndex, pipIndex)
+
+			// The final image gets the system packages before the built Python packages, and
+			// not the build packages
+			require.NotContains(t, finalStage, "libpq-dev")
+			aptIndex := strings.Index(finalStage, "apt-get install -qqy libpq5")
+			copyIndex := strings.Index(finalStage, "from=deps")
+			require.NotEqual(t, -1, aptIndex)
+			require.NotEqual(t, -1, copyIndex)
+			require.Less(t, aptIndex, copyIndex)
+		})
+	}
+}
+
+func TestGenerateAptSources(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  system_packages:
+    - example-tool
+  apt_keys:
+    - https://example.com/apt/key.asc
+  apt_repositories:
+    - deb https://example.com/apt stable main
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+	gen.SetUseCogBaseImage(false)
+	actual, err := gen.GenerateDockerfileWithoutSeparateWeights()
+	require.NoError(t, err)
+
+	keyLine := "ADD --chmod=644 https://example.com/apt/key.asc /etc/apt/keyrings/cog-0.asc"
+	repoLine := `RUN printf '%s\n' 'deb [signed-by=/etc/apt/keyrings/cog-0.asc] https://example.com/apt stable main' > /etc/apt/sources.list.d/cog.list`
+	keyIndex := strings.Index(actual, keyLine)
+	repoIndex := strings.Index(actual, repoLine)
+	aptIndex := strings.Index(actual, "apt-get install -qqy example-tool")
+	require.NotEqual(t, -1, keyIndex)
+	require.NotEqual(t, -1, repoIndex)
+	require.NotEqual(t, -1, aptIndex)
+	require.Less(t, keyIndex, repoIndex)
+	require.Less(t, repoIndex, aptIndex)
+}
+
+func TestGenerateAptSourcesWithOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  system_packages:
+    - example-tool
+  apt_keys:
+    - https://example.com/apt/key.asc
+  apt_repositories:
+    - deb [arch=amd64] https://example.com/apt stable main
+    - deb [ arch=amd64 ] https://example.com/other stable main
+    - deb [arch=amd64 signed-by=/usr/share/keyrings/other.gpg] https://example.com/signed stable main
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+	gen.SetUseCogBaseImage(false)
+	actual, err := gen.GenerateDockerfileWithoutSeparateWeights()
+	require.NoError(t, err)
+
+	// signed-by is added to the existing options, unless it's already there
+	require.Contains(t, actual, `'deb [arch=amd64 signed-by=/etc/apt/keyrings/cog-0.asc] https://example.com/apt stable main'`)
+	require.Contains(t, actual, `'deb [ arch=amd64 signed-by=/etc/apt/keyrings/cog-0.asc] https://example.com/other stable main'`)
+	require.Contains(t, actual, `'deb [arch=amd64 signed-by=/usr/share/keyrings/other.gpg] https://example.com/signed stable main'`)
+}
+
+func TestGeneratePythonIndexes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  python_packages:
+    - pandas==2.0.3
+  python_index_url: https://pypi.example.com/simple
+  python_extra_index_urls:
+    - https://wheels.example.com/simple
+  python_trusted_hosts:
+    - pypi.example.com
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+	actual, err := gen.GenerateDockerfileWithoutSeparateWeights()
+	require.NoError(t, err)
+
+	require.Contains(t, actual, "RUN --mount=type=cache,target=/root/.cache/pip --mount=type=secret,id=cog-pip-conf PIP_CONFIG_FILE=/run/secrets/cog-pip-conf pip install -t /dep -r /tmp/requirements.txt\n")
+	require.NotContains(t, actual, "example.com")
+
+	// The indexes are passed in a build secret outside the build context
+	secrets := gen.Secrets()
+	require.Len(t, secrets, 1)
+	pipConfigPath := strings.TrimPrefix(secrets[0], "id=cog-pip-conf,src=")
+	require.False(t, strings.HasPrefix(pipConfigPath, tmpDir))
+	pipConfig, err := os.ReadFile(pipConfigPath)
+	require.NoError(t, err)
+	require.Equal(t, `[global]
+index-url = https://pypi.example.com/simple
+extra-index-url = https://wheels.example.com/simple
+trusted-host = pypi.example.com
+`, string(pipConfig))
+
+	require.NoError(t, gen.Cleanup())
+	require.NoFileExists(t, pipConfigPath)
+}
+
+func TestGeneratePostInstall(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  post_install:
+    - python -c "import nltk; nltk.download('punkt')"
+    - python setup.py build_ext --inplace
+  system_packages:
+    - ffmpeg
+  python_packages:
+    - nltk==3.8.1
+  run:
+    - "cowsay moo"
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+
+	expectedEnd := `COPY . /src
+RUN python -c "import nltk; nltk.download('punkt')"
+RUN python setup.py build_ext --inplace`
+
+	actual, err := gen.GenerateDockerfileWithoutSeparateWeights()
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(actual, expectedEnd), actual)
+
+	_, actual, _, err = gen.GenerateModelBaseWithSeparateWeights("r8.im/replicate/cog-test")
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(actual, expectedEnd), actual)
+}
+
+func TestGenerateRemoveAfter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	conf, err := config.FromYAML([]byte(`
+build:
+  gpu: false
+  system_packages:
+    - build-essential
+  post_install:
+    - python setup.py build_ext --inplace
+  remove_after:
+    - /usr/share/doc
+    - build/*.o
+predict: predict.py:Predictor
+`))
+	require.NoError(t, err)
+	require.NoError(t, conf.ValidateAndComplete(""))
+
+	gen, err := NewGenerator(conf, tmpDir)
+	require.NoError(t, err)
+
+	expectedEnd := `COPY . /src
+RUN python setup.py build_ext --inplace
+RUN rm -rf /usr/share/doc build/*.o`
+
+	actual, err := gen.GenerateDockerfileWithoutSeparateWeights()
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(actual, expectedEnd), actual)
+
+	_, actual, _, err = gen.GenerateModelBaseWithSeparateWeights("r8.im/replicate/cog-test")
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(actual, expectedEnd), actual)
+}
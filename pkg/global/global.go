@@ -9,6 +9,8 @@ var (
 	Commit                = ""
 	BuildTime             = "none"
 	Debug                 = false
+	Quiet                 = false
+	Color                 = "auto"
 	ProfilingEnabled      = false
 	StartupTimeout        = 5 * time.Minute
 	ConfigFilename        = "cog.yaml"
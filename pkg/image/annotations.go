@@ -0,0 +1,43 @@
+package image
+
+import (
+	"context"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/replicate/cog/pkg/registry"
+)
+
+// PushWithAnnotations pushes imageName from the local Docker daemon to its registry, adding
+// annotations to the image manifest.
+//
+// Annotations are different from labels. Labels are part of the image config, so they're
+// baked into the image ID and visible to `docker inspect`. Annotations are metadata on the
+// manifest in the registry, so they can be added without changing the image itself.
+func PushWithAnnotations(ctx context.Context, imageName string, annotations map[string]string) error {
+	tmpDir, err := os.MkdirTemp("", "cog-push")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img, tag, err := imageFromDocker(imageName, tmpDir)
+	if err != nil {
+		return err
+	}
+	return registry.Write(ctx, tag.String(), annotate(img, annotations))
+}
+
+func annotate(img v1.Image, annotations map[string]string) v1.Image {
+	if len(annotations) == 0 {
+		return img
+	}
+	// Images from the Docker daemon have Docker media types, which don't have annotations,
+	// so registries may drop them or reject the manifest. Make it an OCI image first.
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, types.OCIConfigJSON)
+	return mutate.Annotations(img, annotations).(v1.Image)
+}
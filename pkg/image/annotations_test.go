@@ -0,0 +1,34 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/registry"
+)
+
+func TestAnnotatedImageRoundTripsThroughRegistry(t *testing.T) {
+	ctx := context.Background()
+	host := startRegistry(t)
+	ref := host + "/user/model:latest"
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	annotations := map[string]string{"org.opencontainers.image.source": "https://github.com/user/model"}
+	require.NoError(t, registry.Write(ctx, ref, annotate(img, annotations)))
+
+	manifest, err := registry.Manifest(ctx, ref)
+	require.NoError(t, err)
+	require.Equal(t, annotations, manifest.Annotations)
+	require.Equal(t, types.OCIManifestSchema1, manifest.MediaType)
+	require.Equal(t, types.OCIConfigJSON, manifest.Config.MediaType)
+
+	// Annotations are on the manifest, not the config
+	config, err := img.ConfigFile()
+	require.NoError(t, err)
+	require.Empty(t, config.Config.Labels)
+}
@@ -25,24 +25,50 @@ const weightsManifestPath = ".cog/cache/weights_manifest.json"
 const bundledSchemaFile = ".cog/openapi_schema.json"
 const bundledSchemaPy = ".cog/schema.py"
 
+// BuildOptions are the options for Build, which mostly come from the flags of cog build and
+// cog push
+type BuildOptions struct {
+	// Secrets are passed to docker build, in the form id=foo,src=/path/to/file
+	Secrets         []string
+	NoCache         bool
+	SeparateWeights bool
+	// UseCudaBaseImage is "true", "false", or "auto"
+	UseCudaBaseImage string
+	// ProgressOutput is the docker build --progress type
+	ProgressOutput string
+	// SchemaFile is an OpenAPI schema to use instead of getting it from the built image
+	SchemaFile string
+	// DockerfileFile is a Dockerfile to build instead of generating one from cog.yaml
+	DockerfileFile  string
+	UseCogBaseImage bool
+	// Squash merges the image's layers into one after it's built
+	Squash bool
+	// CompressSchemaLabel gzips the OpenAPI schema in the run.cog.openapi_schema label
+	CompressSchemaLabel bool
+	// StrictOutputs fails the build if the output schema is opaque, like a bare dict
+	StrictOutputs bool
+}
+
 // Build a Cog model from a config
 //
 // This is separated out from docker.Build(), so that can be as close as possible to the behavior of 'docker build'.
-func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache, separateWeights bool, useCudaBaseImage string, progressOutput string, schemaFile string, dockerfileFile string, useCogBaseImage bool) error {
+func Build(cfg *config.Config, dir, imageName string, opts BuildOptions) error {
 	console.Infof("Building Docker image from environment in cog.yaml as %s...", imageName)
 
+	secrets := opts.Secrets
+
 	// remove bundled schema files that may be left from previous builds
 	_ = os.Remove(bundledSchemaFile)
 	_ = os.Remove(bundledSchemaPy)
 
 	var cogBaseImageName string
 
-	if dockerfileFile != "" {
-		dockerfileContents, err := os.ReadFile(dockerfileFile)
+	if opts.DockerfileFile != "" {
+		dockerfileContents, err := os.ReadFile(opts.DockerfileFile)
 		if err != nil {
-			return fmt.Errorf("Failed to read Dockerfile at %s: %w", dockerfileFile, err)
+			return fmt.Errorf("Failed to read Dockerfile at %s: %w", opts.DockerfileFile, err)
 		}
-		if err := docker.Build(dir, string(dockerfileContents), imageName, secrets, noCache, progressOutput, config.BuildSourceEpochTimestamp); err != nil {
+		if err := docker.Build(dir, string(dockerfileContents), imageName, secrets, opts.NoCache, opts.ProgressOutput, config.BuildSourceEpochTimestamp); err != nil {
 			return fmt.Errorf("Failed to build Docker image: %w", err)
 		}
 	} else {
@@ -55,8 +81,8 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 				console.Warnf("Error cleaning up Dockerfile generator: %s", err)
 			}
 		}()
-		generator.SetUseCudaBaseImage(useCudaBaseImage)
-		generator.SetUseCogBaseImage(useCogBaseImage)
+		generator.SetUseCudaBaseImage(opts.UseCudaBaseImage)
+		generator.SetUseCogBaseImage(opts.UseCogBaseImage)
 
 		if generator.IsUsingCogBaseImage() {
 			cogBaseImageName, err = generator.BaseImage()
@@ -65,11 +91,12 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 			}
 		}
 
-		if separateWeights {
+		if opts.SeparateWeights {
 			weightsDockerfile, runnerDockerfile, dockerignore, err := generator.GenerateModelBaseWithSeparateWeights(imageName)
 			if err != nil {
 				return fmt.Errorf("Failed to generate Dockerfile: %w", err)
 			}
+			secrets = append(secrets, generator.Secrets()...)
 
 			if err := backupDockerignore(); err != nil {
 				return fmt.Errorf("Failed to backup .dockerignore file: %w", err)
@@ -82,7 +109,7 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 			cachedManifest, _ := weights.LoadManifest(weightsManifestPath)
 			changed := cachedManifest == nil || !weightsManifest.Equal(cachedManifest)
 			if changed {
-				if err := buildWeightsImage(dir, weightsDockerfile, imageName+"-weights", secrets, noCache, progressOutput); err != nil {
+				if err := buildWeightsImage(dir, weightsDockerfile, imageName+"-weights", secrets, opts.NoCache, opts.ProgressOutput); err != nil {
 					return fmt.Errorf("Failed to build model weights Docker image: %w", err)
 				}
 				err := weightsManifest.Save(weightsManifestPath)
@@ -93,7 +120,7 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 				console.Info("Weights unchanged, skip rebuilding and use cached image...")
 			}
 
-			if err := buildRunnerImage(dir, runnerDockerfile, dockerignore, imageName, secrets, noCache, progressOutput); err != nil {
+			if err := buildRunnerImage(dir, runnerDockerfile, dockerignore, imageName, secrets, opts.NoCache, opts.ProgressOutput); err != nil {
 				return fmt.Errorf("Failed to build runner Docker image: %w", err)
 			}
 		} else {
@@ -101,27 +128,30 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 			if err != nil {
 				return fmt.Errorf("Failed to generate Dockerfile: %w", err)
 			}
-			if err := docker.Build(dir, dockerfileContents, imageName, secrets, noCache, progressOutput, config.BuildSourceEpochTimestamp); err != nil {
+			secrets = append(secrets, generator.Secrets()...)
+			if err := docker.Build(dir, dockerfileContents, imageName, secrets, opts.NoCache, opts.ProgressOutput, config.BuildSourceEpochTimestamp); err != nil {
 				return fmt.Errorf("Failed to build Docker image: %w", err)
 			}
 		}
 	}
 
 	var schemaJSON []byte
-	if schemaFile != "" {
-		console.Infof("Validating model schema from %s...", schemaFile)
-		data, err := os.ReadFile(schemaFile)
+	if opts.SchemaFile != "" {
+		console.Infof("Validating model schema from %s...", opts.SchemaFile)
+		data, err := os.ReadFile(opts.SchemaFile)
 		if err != nil {
 			return fmt.Errorf("Failed to read schema file: %w", err)
 		}
 
 		schemaJSON = data
 	} else {
-		console.Info("Validating model schema...")
+		spinner := console.NewSpinner("Validating model schema...")
 		schema, err := GenerateOpenAPISchema(imageName, cfg.Build.GPU)
 		if err != nil {
+			spinner.Fail()
 			return fmt.Errorf("Failed to get type signature: %w", err)
 		}
+		spinner.Finish()
 
 		data, err := json.Marshal(schema)
 		if err != nil {
@@ -131,6 +161,12 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 		schemaJSON = data
 	}
 
+	if opts.StrictOutputs {
+		if err := checkStrictOutputs(schemaJSON); err != nil {
+			return err
+		}
+	}
+
 	// save open_api schema file
 	err := os.WriteFile(bundledSchemaFile, schemaJSON, 0o644)
 	if err != nil {
@@ -158,10 +194,15 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 		return fmt.Errorf("Failed to convert config to JSON: %w", err)
 	}
 
+	schemaLabel, err := encodeSchemaLabel(schemaJSON, opts.CompressSchemaLabel)
+	if err != nil {
+		return fmt.Errorf("Failed to compress schema: %w", err)
+	}
+
 	labels := map[string]string{
 		global.LabelNamespace + "version":        global.Version,
 		global.LabelNamespace + "config":         string(bytes.TrimSpace(configJSON)),
-		global.LabelNamespace + "openapi_schema": string(schemaJSON),
+		global.LabelNamespace + "openapi_schema": schemaLabel,
 		// Mark the image as having an appropriate init entrypoint. We can use this
 		// to decide how/if to shim the image.
 		global.LabelNamespace + "has_init": "true",
@@ -214,6 +255,15 @@ func Build(cfg *config.Config, dir, imageName string, secrets []string, noCache,
 	if err := docker.BuildAddLabelsAndSchemaToImage(imageName, labels, bundledSchemaFile, bundledSchemaPy); err != nil {
 		return fmt.Errorf("Failed to add labels to image: %w", err)
 	}
+
+	if opts.Squash {
+		spinner := console.NewSpinner("Squashing image layers...")
+		if err := Squash(imageName); err != nil {
+			spinner.Fail()
+			return err
+		}
+		spinner.Finish()
+	}
 	return nil
 }
 
@@ -240,7 +290,7 @@ func BuildBase(cfg *config.Config, dir string, useCudaBaseImage string, useCogBa
 	if err != nil {
 		return "", fmt.Errorf("Failed to generate Dockerfile: %w", err)
 	}
-	if err := docker.Build(dir, dockerfileContents, imageName, []string{}, false, progressOutput, config.BuildSourceEpochTimestamp); err != nil {
+	if err := docker.Build(dir, dockerfileContents, imageName, generator.Secrets(), false, progressOutput, config.BuildSourceEpochTimestamp); err != nil {
 		return "", fmt.Errorf("Failed to build Docker image: %w", err)
 	}
 	return imageName, nil
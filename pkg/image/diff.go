@@ -0,0 +1,141 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/replicate/cog/pkg/registry"
+)
+
+// Change is a single difference between two images. A is nil if the value was only in the
+// second image, and B is nil if it was only in the first. An empty value, like FOO= in env,
+// is a pointer to an empty string.
+type Change struct {
+	Field string  `json:"field"`
+	Key   string  `json:"key,omitempty"`
+	A     *string `json:"a,omitempty"`
+	B     *string `json:"b,omitempty"`
+}
+
+// Diff fetches the manifests and configs of refA and refB from their registries, and returns
+// the differences in their layers, env, entrypoint, cmd, labels, and exposed ports.
+func Diff(ctx context.Context, refA string, refB string) ([]Change, error) {
+	manifestA, configA, err := fetchImage(ctx, refA)
+	if err != nil {
+		return nil, err
+	}
+	manifestB, configB, err := fetchImage(ctx, refB)
+	if err != nil {
+		return nil, err
+	}
+	return diffImages(manifestA, configA, manifestB, configB), nil
+}
+
+func fetchImage(ctx context.Context, ref string) (*v1.Manifest, *v1.ConfigFile, error) {
+	// Resolve the tag once, so the manifest and config are from the same image even if the
+	// tag is pushed to in between
+	img, err := registry.Image(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get manifest for %s: %w", ref, err)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get config for %s: %w", ref, err)
+	}
+	return manifest, config, nil
+}
+
+func diffImages(manifestA *v1.Manifest, configA *v1.ConfigFile, manifestB *v1.Manifest, configB *v1.ConfigFile) []Change {
+	changes := []Change{}
+
+	// Layers are compared by position, because a change to one layer changes every layer after it
+	layers := max(len(manifestA.Layers), len(manifestB.Layers))
+	for i := 0; i < layers; i++ {
+		var a, b *string
+		if i < len(manifestA.Layers) {
+			a = ptr(manifestA.Layers[i].Digest.String())
+		}
+		if i < len(manifestB.Layers) {
+			b = ptr(manifestB.Layers[i].Digest.String())
+		}
+		if a == nil || b == nil || *a != *b {
+			changes = append(changes, Change{Field: "layer", Key: fmt.Sprint(i), A: a, B: b})
+		}
+	}
+
+	changes = append(changes, diffMaps("env", envMap(configA.Config.Env), envMap(configB.Config.Env))...)
+	if a, b := strings.Join(configA.Config.Entrypoint, " "), strings.Join(configB.Config.Entrypoint, " "); a != b {
+		changes = append(changes, Change{Field: "entrypoint", A: &a, B: &b})
+	}
+	if a, b := strings.Join(configA.Config.Cmd, " "), strings.Join(configB.Config.Cmd, " "); a != b {
+		changes = append(changes, Change{Field: "cmd", A: &a, B: &b})
+	}
+	changes = append(changes, diffMaps("label", configA.Config.Labels, configB.Config.Labels)...)
+	changes = append(changes, diffMaps("exposed_port", portMap(configA.Config.ExposedPorts), portMap(configB.Config.ExposedPorts))...)
+
+	return changes
+}
+
+func diffMaps(field string, a map[string]string, b map[string]string) []Change {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	changes := []Change{}
+	for _, k := range sorted {
+		// Check presence rather than comparing values, so a key set to an empty value in one
+		// image and missing from the other is still a change
+		va, okA := a[k]
+		vb, okB := b[k]
+		if okA == okB && va == vb {
+			continue
+		}
+		change := Change{Field: field, Key: k}
+		if okA {
+			change.A = &va
+		}
+		if okB {
+			change.B = &vb
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func envMap(env []string) map[string]string {
+	m := map[string]string{}
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		m[k] = v
+	}
+	return m
+}
+
+func portMap(ports map[string]struct{}) map[string]string {
+	m := map[string]string{}
+	for port := range ports {
+		m[port] = "exposed"
+	}
+	return m
+}
+
+func ptr(s string) *string {
+	return &s
+}
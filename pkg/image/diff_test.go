@@ -0,0 +1,53 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/registry"
+)
+
+func TestDiffImagesDifferingByLabel(t *testing.T) {
+	ctx := context.Background()
+	host := startRegistry(t)
+	refA := host + "/user/model:a"
+	refB := host + "/user/model:b"
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	config, err := img.ConfigFile()
+	require.NoError(t, err)
+	config.Config.Env = []string{"PATH=/usr/bin"}
+	config.Config.Labels = map[string]string{"run.cog.version": "0.9.0", "run.cog.config": "{}"}
+	imgA, err := mutate.ConfigFile(img, config)
+	require.NoError(t, err)
+
+	config = config.DeepCopy()
+	config.Config.Labels["run.cog.version"] = "0.10.0"
+	imgB, err := mutate.ConfigFile(img, config)
+	require.NoError(t, err)
+
+	require.NoError(t, registry.Write(ctx, refA, imgA))
+	require.NoError(t, registry.Write(ctx, refB, imgB))
+
+	changes, err := Diff(ctx, refA, refB)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Field: "label", Key: "run.cog.version", A: ptr("0.9.0"), B: ptr("0.10.0")}}, changes)
+
+	changes, err = Diff(ctx, refA, refA)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestDiffMapsTellsEmptyValuesFromMissingKeys(t *testing.T) {
+	a := map[string]string{"FOO": "", "BAR": "1"}
+	b := map[string]string{"BAR": "1", "BAZ": ""}
+	require.Equal(t, []Change{
+		{Field: "env", Key: "BAZ", B: ptr("")},
+		{Field: "env", Key: "FOO", A: ptr("")},
+	}, diffMaps("env", a, b))
+}
@@ -0,0 +1,118 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/replicate/cog/pkg/docker"
+)
+
+// ExportOCI writes imageName from the local Docker daemon to dest as an OCI image layout tarball.
+// annotations are added to the image manifest.
+func ExportOCI(imageName string, dest string, annotations map[string]string) error {
+	tmpDir, err := os.MkdirTemp("", "cog-oci")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img, tag, err := imageFromDocker(imageName, tmpDir)
+	if err != nil {
+		return err
+	}
+	if err := writeOCILayoutTar(annotate(img, annotations), tag.String(), filepath.Join(tmpDir, "layout"), dest); err != nil {
+		return fmt.Errorf("Failed to export %s to %s: %w", imageName, dest, err)
+	}
+	return nil
+}
+
+// imageFromDocker saves imageName from the local Docker daemon to tmpDir, and returns it
+// as an image that reads from there. tmpDir must outlive the returned image.
+func imageFromDocker(imageName string, tmpDir string) (v1.Image, name.Tag, error) {
+	tag, err := name.NewTag(imageName)
+	if err != nil {
+		return nil, name.Tag{}, fmt.Errorf("Failed to parse image name %s: %w", imageName, err)
+	}
+	savedPath := filepath.Join(tmpDir, "image.tar")
+	if err := docker.Save(imageName, savedPath); err != nil {
+		return nil, name.Tag{}, fmt.Errorf("Failed to save %s: %w", imageName, err)
+	}
+	img, err := tarball.Image(func() (io.ReadCloser, error) { return os.Open(savedPath) }, &tag)
+	if err != nil {
+		return nil, name.Tag{}, fmt.Errorf("Failed to read %s: %w", imageName, err)
+	}
+	return img, tag, nil
+}
+
+// writeOCILayoutTar writes img to an OCI image layout in layoutDir, then archives it to dest.
+// refName is recorded in the index so tools like skopeo and crane can find the image by name.
+func writeOCILayoutTar(img v1.Image, refName string, layoutDir string, dest string) error {
+	p, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		return err
+	}
+	if err := p.AppendImage(img, layout.WithAnnotations(map[string]string{
+		"org.opencontainers.image.ref.name": refName,
+	})); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tarDirectory(layoutDir, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func tarDirectory(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(relPath)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
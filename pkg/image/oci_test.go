@@ -0,0 +1,77 @@
+package image
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOCILayoutTar(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	img, err = mutate.Config(img, v1.Config{
+		Labels: map[string]string{"run.cog.version": "dev"},
+	})
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+	dest := filepath.Join(tmpDir, "image.tar")
+	require.NoError(t, writeOCILayoutTar(img, "r8.im/user/model:latest", filepath.Join(tmpDir, "layout"), dest))
+
+	// Extract the tarball and read it back as an OCI layout
+	extracted := filepath.Join(tmpDir, "extracted")
+	untar(t, dest, extracted)
+	require.FileExists(t, filepath.Join(extracted, "oci-layout"))
+
+	index, err := layout.ImageIndexFromPath(extracted)
+	require.NoError(t, err)
+	manifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Manifests, 1)
+	require.Equal(t, "r8.im/user/model:latest", manifest.Manifests[0].Annotations["org.opencontainers.image.ref.name"])
+
+	exported, err := index.Image(manifest.Manifests[0].Digest)
+	require.NoError(t, err)
+	cfg, err := exported.ConfigFile()
+	require.NoError(t, err)
+	require.Equal(t, "dev", cfg.Config.Labels["run.cog.version"])
+
+	expectedDigest, err := img.Digest()
+	require.NoError(t, err)
+	require.Equal(t, expectedDigest, manifest.Manifests[0].Digest)
+}
+
+func untar(t *testing.T, src string, dest string) {
+	t.Helper()
+	f, err := os.Open(src)
+	require.NoError(t, err)
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		target := filepath.Join(dest, hdr.Name)
+		if hdr.Typeflag == tar.TypeDir {
+			require.NoError(t, os.MkdirAll(target, 0o755))
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+		out, err := os.Create(target)
+		require.NoError(t, err)
+		_, err = io.Copy(out, tr)
+		require.NoError(t, err)
+		require.NoError(t, out.Close())
+	}
+}
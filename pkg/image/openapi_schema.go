@@ -68,5 +68,9 @@ func GetOpenAPISchema(imageName string) (*openapi3.T, error) {
 	if schemaString == "" {
 		return nil, fmt.Errorf("Image %s does not appear to be a Cog model", imageName)
 	}
-	return openapi3.NewLoader().LoadFromData([]byte(schemaString))
+	schemaJSON, err := decodeSchemaLabel(schemaString)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema from %s: %w", imageName, err)
+	}
+	return openapi3.NewLoader().LoadFromData(schemaJSON)
 }
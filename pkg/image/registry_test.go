@@ -0,0 +1,20 @@
+package image
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/require"
+)
+
+// startRegistry starts an in-memory registry for the test and returns its host
+func startRegistry(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return u.Host
+}
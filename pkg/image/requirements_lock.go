@@ -0,0 +1,33 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/replicate/cog/pkg/docker"
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+// WriteRequirementsLock writes the exact versions of the Python packages installed in an image
+// to dest, in pip requirements format. Cog itself is left out, because it's installed from a
+// wheel that only exists while building, so the file can be installed from anywhere.
+func WriteRequirementsLock(imageName string, dest string) error {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	err := docker.RunWithIO(docker.RunOptions{
+		Image: imageName,
+		Args: []string{
+			"python", "-m", "pip", "freeze", "--exclude", "cog",
+		},
+	}, nil, &stdout, &stderr)
+	if err != nil {
+		console.Info(stderr.String())
+		return fmt.Errorf("Failed to list Python packages in %s: %w", imageName, err)
+	}
+	if err := os.WriteFile(dest, stdout.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("Failed to write requirements lock to %s: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package image
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compressedSchemaPrefix marks a run.cog.openapi_schema label that has been gzipped and
+// base64-encoded. To decode it, strip the prefix, base64-decode the rest, then gunzip it:
+//
+//	docker inspect --format '{{ index .Config.Labels "run.cog.openapi_schema" }}' IMAGE \
+//	  | sed 's/^gzip+base64://' | base64 -d | gunzip
+const compressedSchemaPrefix = "gzip+base64:"
+
+// encodeSchemaLabel returns the value of the run.cog.openapi_schema label for schemaJSON,
+// optionally compressed to keep large schemas from bloating the image config.
+func encodeSchemaLabel(schemaJSON []byte, compress bool) (string, error) {
+	if !compress {
+		return string(schemaJSON), nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(schemaJSON); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return compressedSchemaPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeSchemaLabel returns the schema JSON in a run.cog.openapi_schema label, whether or
+// not it was compressed.
+func decodeSchemaLabel(label string) ([]byte, error) {
+	encoded, compressed := strings.CutPrefix(label, compressedSchemaPrefix)
+	if !compressed {
+		return []byte(label), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode compressed schema: %w", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress schema: %w", err)
+	}
+	defer gz.Close()
+	schemaJSON, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress schema: %w", err)
+	}
+	return schemaJSON, nil
+}
@@ -0,0 +1,31 @@
+package image
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaLabelRoundTrip(t *testing.T) {
+	schemaJSON := []byte(`{"openapi":"3.0.2","info":{"title":"Cog","version":"0.1.0"},"paths":{}}`)
+
+	label, err := encodeSchemaLabel(schemaJSON, false)
+	require.NoError(t, err)
+	require.Equal(t, string(schemaJSON), label)
+	decoded, err := decodeSchemaLabel(label)
+	require.NoError(t, err)
+	require.Equal(t, schemaJSON, decoded)
+
+	label, err = encodeSchemaLabel(schemaJSON, true)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(label, "gzip+base64:"))
+	decoded, err = decodeSchemaLabel(label)
+	require.NoError(t, err)
+	require.Equal(t, schemaJSON, decoded)
+}
+
+func TestDecodeSchemaLabelInvalid(t *testing.T) {
+	_, err := decodeSchemaLabel("gzip+base64:not base64!")
+	require.ErrorContains(t, err, "Failed to decode compressed schema")
+}
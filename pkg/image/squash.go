@@ -0,0 +1,102 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/replicate/cog/pkg/docker"
+)
+
+// Squash replaces imageName in the local Docker daemon with an equivalent image that has a single layer.
+//
+// The filesystem and the image config (env, entrypoint, labels, etc) are preserved, but the layer history is not.
+func Squash(imageName string) error {
+	tag, err := name.NewTag(imageName)
+	if err != nil {
+		return fmt.Errorf("Failed to parse image name %s: %w", imageName, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "cog-squash")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	savedPath := filepath.Join(tmpDir, "image.tar")
+	if err := docker.Save(imageName, savedPath); err != nil {
+		return fmt.Errorf("Failed to save %s: %w", imageName, err)
+	}
+	img, err := tarball.ImageFromPath(savedPath, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to read %s: %w", imageName, err)
+	}
+
+	// Flattening is expensive, so write the merged filesystem to disk once rather than
+	// recomputing it every time the layer is read.
+	fs, err := os.Create(filepath.Join(tmpDir, "fs.tar"))
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	squashed, err := squashImage(img, fs)
+	if err != nil {
+		return fmt.Errorf("Failed to squash %s: %w", imageName, err)
+	}
+
+	squashedPath := filepath.Join(tmpDir, "squashed.tar")
+	if err := tarball.WriteToFile(squashedPath, tag, squashed); err != nil {
+		return fmt.Errorf("Failed to write squashed image %s: %w", imageName, err)
+	}
+	if err := docker.Load(squashedPath); err != nil {
+		return fmt.Errorf("Failed to load squashed image %s: %w", imageName, err)
+	}
+	return nil
+}
+
+// squashImage merges the layers of img into one, using f as scratch space for the flattened filesystem.
+func squashImage(img v1.Image, f *os.File) (v1.Image, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	flattened := mutate.Extract(img)
+	defer flattened.Close()
+	if _, err := io.Copy(f, flattened); err != nil {
+		return nil, fmt.Errorf("Failed to flatten filesystem: %w", err)
+	}
+
+	layer, err := tarball.LayerFromFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	squashed, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	squashedCfg, err := squashed.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	squashedCfg = squashedCfg.DeepCopy()
+	squashedCfg.Architecture = cfg.Architecture
+	squashedCfg.OS = cfg.OS
+	squashedCfg.OSVersion = cfg.OSVersion
+	squashedCfg.Variant = cfg.Variant
+	squashedCfg.Author = cfg.Author
+	squashedCfg.Created = cfg.Created
+	squashedCfg.Config = cfg.Config
+
+	return mutate.ConfigFile(squashed, squashedCfg)
+}
@@ -0,0 +1,65 @@
+package image
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquashImage(t *testing.T) {
+	img, err := random.Image(1024, 3)
+	require.NoError(t, err)
+	img, err = mutate.Config(img, v1.Config{
+		Env:        []string{"FOO=bar"},
+		Entrypoint: []string{"/sbin/tini", "--"},
+		Labels:     map[string]string{"run.cog.has_init": "true"},
+	})
+	require.NoError(t, err)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "squash.tar"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	squashed, err := squashImage(img, f)
+	require.NoError(t, err)
+
+	layers, err := squashed.Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	squashedCfg, err := squashed.ConfigFile()
+	require.NoError(t, err)
+	require.Equal(t, cfg.Config, squashedCfg.Config)
+	require.Len(t, squashedCfg.RootFS.DiffIDs, 1)
+
+	require.Equal(t, readFilesystem(t, img), readFilesystem(t, squashed))
+}
+
+func readFilesystem(t *testing.T, img v1.Image) map[string]string {
+	t.Helper()
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	files := map[string]string{}
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		contents, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(contents)
+	}
+	return files
+}
@@ -0,0 +1,113 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const componentsPrefix = "#/components/schemas/"
+
+// checkStrictOutputs returns an error if the output schema in schemaJSON doesn't describe
+// what the model returns, because somewhere in it there's a bare dict or Any. Those are
+// fine for experimenting, but clients of production models can't do anything useful with them.
+func checkStrictOutputs(schemaJSON []byte) error {
+	var schema struct {
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return fmt.Errorf("Failed to parse schema: %w", err)
+	}
+	walker := opaqueWalker{schemas: schema.Components.Schemas, seen: map[string]bool{}}
+	for _, name := range []string{"Output", "TrainingOutput"} {
+		output, ok := walker.schemas[name]
+		if !ok {
+			continue
+		}
+		if path, kind := walker.walk(output, name); path != "" {
+			return fmt.Errorf("%s in the output schema is %s, which doesn't describe what the model returns. Use a specific type, like a BaseModel with typed fields, or build without --strict", path, kind)
+		}
+	}
+	return nil
+}
+
+type opaqueWalker struct {
+	schemas map[string]map[string]any
+	seen    map[string]bool
+}
+
+// walk returns the path to the first opaque schema in node and what it is, or "" if
+// everything in node has a type
+func (w opaqueWalker) walk(node map[string]any, path string) (string, string) {
+	if ref, ok := node["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, componentsPrefix)
+		if w.seen[name] {
+			return "", ""
+		}
+		w.seen[name] = true
+		if schema, ok := w.schemas[name]; ok {
+			return w.walk(schema, name)
+		}
+		return "", ""
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if items, ok := node[key].([]any); ok {
+			for i, item := range items {
+				if p, kind := w.walkAny(item, fmt.Sprintf("%s.%s[%d]", path, key, i)); p != "" {
+					return p, kind
+				}
+			}
+			return "", ""
+		}
+	}
+	if _, ok := node["enum"]; ok {
+		return "", ""
+	}
+
+	switch node["type"] {
+	case nil:
+		return path, "Any"
+	case "object":
+		properties, _ := node["properties"].(map[string]any)
+		if len(properties) == 0 {
+			if additional, ok := node["additionalProperties"].(map[string]any); ok {
+				return w.walk(additional, path+".*")
+			}
+			return path, "a dict with no properties"
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, kind := w.walkAny(properties[name], path+"."+name); p != "" {
+				return p, kind
+			}
+		}
+	case "array":
+		switch items := node["items"].(type) {
+		case map[string]any:
+			return w.walk(items, path+"[]")
+		case []any:
+			for i, item := range items {
+				if p, kind := w.walkAny(item, fmt.Sprintf("%s[%d]", path, i)); p != "" {
+					return p, kind
+				}
+			}
+		default:
+			return path + "[]", "Any"
+		}
+	}
+	return "", ""
+}
+
+func (w opaqueWalker) walkAny(node any, path string) (string, string) {
+	if m, ok := node.(map[string]any); ok {
+		return w.walk(m, path)
+	}
+	return "", ""
+}
@@ -0,0 +1,69 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStrictOutputs(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		output string
+		extra  string
+		err    string
+	}{
+		{
+			name:   "string",
+			output: `{"title": "Output", "type": "string"}`,
+		},
+		{
+			name:   "any",
+			output: `{"title": "Output"}`,
+			err:    "Output in the output schema is Any",
+		},
+		{
+			name:   "dict",
+			output: `{"title": "Output", "type": "object"}`,
+			err:    "Output in the output schema is a dict with no properties",
+		},
+		{
+			name:   "dict of strings",
+			output: `{"title": "Output", "type": "object", "additionalProperties": {"type": "string"}}`,
+		},
+		{
+			name:   "list of dicts",
+			output: `{"title": "Output", "type": "array", "items": {"type": "object"}}`,
+			err:    "Output[] in the output schema is a dict with no properties",
+		},
+		{
+			name:   "tuple with any",
+			output: `{"title": "Output", "type": "array", "items": [{"type": "string"}, {}]}`,
+			err:    "Output[1] in the output schema is Any",
+		},
+		{
+			name:   "model with typed fields",
+			output: `{"title": "Output", "$ref": "#/components/schemas/Prediction"}`,
+			extra:  `"Prediction": {"type": "object", "properties": {"label": {"type": "string"}}}`,
+		},
+		{
+			name:   "model with a dict field",
+			output: `{"title": "Output", "$ref": "#/components/schemas/Prediction"}`,
+			extra:  `"Prediction": {"type": "object", "properties": {"label": {"type": "string"}, "metadata": {"title": "Metadata", "type": "object"}}}`,
+			err:    "Prediction.metadata in the output schema is a dict with no properties",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			schemas := `"Output": ` + tt.output
+			if tt.extra != "" {
+				schemas += ", " + tt.extra
+			}
+			err := checkStrictOutputs([]byte(`{"components": {"schemas": {` + schemas + `}}}`))
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -18,6 +18,11 @@ import (
 
 type status string
 
+const (
+	StatusSucceeded status = "succeeded"
+	StatusFailed    status = "failed"
+)
+
 type HealthcheckResponse struct {
 	Status string `json:"status"`
 }
@@ -31,6 +36,7 @@ type Response struct {
 	Status status       `json:"status"`
 	Output *interface{} `json:"output"`
 	Error  string       `json:"error"`
+	Logs   string       `json:"logs"`
 }
 
 type ValidationErrorResponse struct {
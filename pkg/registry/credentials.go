@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+// cloudCredentialHelpers are the Docker credential helpers for cloud registries, in the
+// order they're tried. They're used when the registry isn't set up in Docker's
+// config.json, so pushing to a cloud registry works if you're logged in to the cloud's CLI.
+var cloudCredentialHelpers = []struct {
+	host    *regexp.Regexp
+	helpers []string
+}{
+	// e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com
+	{regexp.MustCompile(`^[0-9]+\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`), []string{"ecr-login"}},
+	// e.g. gcr.io, us.gcr.io, us-central1-docker.pkg.dev
+	{regexp.MustCompile(`^([a-z0-9-]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`), []string{"gcloud", "gcr"}},
+	// e.g. myregistry.azurecr.io
+	{regexp.MustCompile(`^[a-z0-9]+\.azurecr\.io$`), []string{"acr-env"}},
+}
+
+// keychain resolves credentials from Docker's config.json first, which includes anything
+// set up with docker login or credHelpers, then the cloud credential helper for the
+// registry if it's installed
+var keychain = authn.NewMultiKeychain(
+	authn.DefaultKeychain,
+	authn.NewKeychainFromHelper(cloudCredentialHelper{}),
+)
+
+// Authenticator returns the credentials used to talk to the registry that ref is in, e.g.
+// r8.im/user/model:v1. It's anonymous if there aren't any.
+func Authenticator(ref string) (authn.Authenticator, error) {
+	reference, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid reference %s: %w", ref, err)
+	}
+	auth, err := keychain.Resolve(reference.Context())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get credentials for %s: %w", reference.Context().RegistryStr(), err)
+	}
+	return auth, nil
+}
+
+type cloudCredentialHelper struct{}
+
+// Get implements authn.Helper by running the first installed cloud credential helper for
+// host. It returns an error if there isn't one, which means anonymous.
+func (cloudCredentialHelper) Get(host string) (string, string, error) {
+	for _, c := range cloudCredentialHelpers {
+		if !c.host.MatchString(host) {
+			continue
+		}
+		for _, helper := range c.helpers {
+			binary := "docker-credential-" + helper
+			if _, err := exec.LookPath(binary); err != nil {
+				continue
+			}
+			return runCredentialHelper(binary, host)
+		}
+	}
+	return "", "", fmt.Errorf("No credential helper for %s", host)
+}
+
+// runCredentialHelper gets the credentials for host from a Docker credential helper:
+// https://docs.docker.com/reference/cli/docker/login/#credential-helper-protocol
+func runCredentialHelper(binary string, host string) (string, string, error) {
+	cmd := exec.Command(binary, "get")
+	cmd.Env = os.Environ()
+	cmd.Stdin = strings.NewReader(host)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	console.Debug("$ " + strings.Join(cmd.Args, " "))
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to run %s: %w: %s", binary, err, strings.TrimSpace(stderr.String()))
+	}
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("Failed to parse output of %s: %w", binary, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
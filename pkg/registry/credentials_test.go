@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/require"
+)
+
+// installCredentialHelpers puts fake credential helpers on PATH that return their own
+// name as the username, and points Docker's config at an empty directory
+func installCredentialHelpers(t *testing.T, helpers ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, helper := range helpers {
+		script := fmt.Sprintf("#!/bin/sh\nread host\necho '{\"ServerURL\": \"'$host'\", \"Username\": \"%s\", \"Secret\": \"secret\"}'\n", helper)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-"+helper), []byte(script), 0o755))
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+}
+
+func TestAuthenticatorUsesCloudCredentialHelper(t *testing.T) {
+	installCredentialHelpers(t, "ecr-login", "gcr", "acr-env")
+
+	for _, tt := range []struct {
+		ref    string
+		helper string
+	}{
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com/model:v1", "ecr-login"},
+		{"gcr.io/project/model:v1", "gcr"},
+		{"us.gcr.io/project/model:v1", "gcr"},
+		{"us-central1-docker.pkg.dev/project/repo/model:v1", "gcr"},
+		{"myregistry.azurecr.io/model:v1", "acr-env"},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			auth, err := Authenticator(tt.ref)
+			require.NoError(t, err)
+			config, err := auth.Authorization()
+			require.NoError(t, err)
+			require.Equal(t, tt.helper, config.Username)
+			require.Equal(t, "secret", config.Password)
+		})
+	}
+}
+
+func TestAuthenticatorPrefersFirstInstalledHelper(t *testing.T) {
+	installCredentialHelpers(t, "gcloud", "gcr")
+
+	auth, err := Authenticator("gcr.io/project/model:v1")
+	require.NoError(t, err)
+	config, err := auth.Authorization()
+	require.NoError(t, err)
+	require.Equal(t, "gcloud", config.Username)
+}
+
+func TestAuthenticatorWithoutCredentials(t *testing.T) {
+	// No helper installed for ECR, and none exists for other registries
+	installCredentialHelpers(t, "gcr")
+
+	for _, ref := range []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/model:v1",
+		"r8.im/user/model:v1",
+	} {
+		auth, err := Authenticator(ref)
+		require.NoError(t, err)
+		require.Equal(t, authn.Anonymous, auth)
+	}
+}
+
+func TestAuthenticatorPrefersDockerConfig(t *testing.T) {
+	installCredentialHelpers(t, "gcr")
+	// user:password
+	config := `{"auths": {"gcr.io": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(os.Getenv("DOCKER_CONFIG"), "config.json"), []byte(config), 0o644))
+
+	auth, err := Authenticator("gcr.io/project/model:v1")
+	require.NoError(t, err)
+	authConfig, err := auth.Authorization()
+	require.NoError(t, err)
+	require.Equal(t, "user", authConfig.Username)
+	require.Equal(t, "password", authConfig.Password)
+}
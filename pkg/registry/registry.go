@@ -0,0 +1,227 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	"github.com/replicate/cog/pkg/util/console"
+)
+
+var (
+	// ErrNotFound is returned when the repository or reference doesn't exist in the registry.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized is returned when the registry rejects our credentials, or we have none.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnsupported is returned when the registry doesn't support the requested operation.
+	ErrUnsupported = errors.New("operation not supported by registry")
+)
+
+// PushConcurrency is how many layers Write uploads at once. cog push sets it with
+// --push-concurrency.
+var PushConcurrency = runtime.NumCPU()
+
+// Mirrors are registry hosts to try, in order, before the registry in a reference when
+// reading an image with Image, Manifest, or ConfigFile, e.g. because the registry is rate
+// limiting us. The next one is only tried if a mirror responds with 429 or 5xx, or can't be
+// reached. They're never used for pushes, and don't affect base image pulls in docker
+// build, which use Docker's own registry-mirrors setting.
+var Mirrors []string
+
+// ListTags returns the tags in repo, e.g. r8.im/user/model
+func ListTags(ctx context.Context, repo string) ([]string, error) {
+	repository, err := name.NewRepository(repo)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid repository %s: %w", repo, err)
+	}
+	tags, err := remote.List(repository, options(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list tags for %s: %w", repo, wrapError(err))
+	}
+	return tags, nil
+}
+
+// Delete deletes the tag or manifest ref, e.g. r8.im/user/model:v1 or r8.im/user/model@sha256:...
+func Delete(ctx context.Context, ref string) error {
+	reference, err := name.ParseReference(ref)
+	if err != nil {
+		return fmt.Errorf("Invalid reference %s: %w", ref, err)
+	}
+	if err := remote.Delete(reference, options(ctx)...); err != nil {
+		return fmt.Errorf("Failed to delete %s: %w", ref, wrapError(err))
+	}
+	return nil
+}
+
+// Write pushes img to ref, e.g. r8.im/user/model:v1. Layers are uploaded concurrently,
+// and layers that are already in the registry are skipped. Progress is shown as a progress
+// bar on interactive terminals.
+func Write(ctx context.Context, ref string, img v1.Image) error {
+	reference, err := name.ParseReference(ref)
+	if err != nil {
+		return fmt.Errorf("Invalid reference %s: %w", ref, err)
+	}
+	// remote.Write closes updates when it's done, whether or not it succeeds
+	updates := make(chan v1.Update, 16)
+	done := make(chan struct{})
+	go func() {
+		showProgress(updates)
+		close(done)
+	}()
+	opts := append(options(ctx), remote.WithJobs(max(PushConcurrency, 1)), remote.WithProgress(updates))
+	err = remote.Write(reference, img, opts...)
+	<-done
+	if err != nil {
+		return fmt.Errorf("Failed to push %s: %w", ref, wrapError(err))
+	}
+	return nil
+}
+
+// showProgress draws a progress bar from push updates until updates is closed. The total
+// grows as layers are found, so the bar shows the percentage of what's known so far.
+func showProgress(updates <-chan v1.Update) {
+	bar := console.NewProgressBar(100)
+	for update := range updates {
+		if update.Error != nil {
+			bar.Fail()
+			continue
+		}
+		if update.Total > 0 {
+			bar.Update(update.Complete * 100 / update.Total)
+		}
+	}
+	bar.Finish()
+}
+
+// Image returns the image for ref, e.g. r8.im/user/model:v1. Its manifest, config, and
+// layers are fetched lazily, so get them all from the one image to resolve ref only once.
+func Image(ctx context.Context, ref string) (v1.Image, error) {
+	reference, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid reference %s: %w", ref, err)
+	}
+	img, err := remoteImage(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get %s: %w", ref, wrapError(err))
+	}
+	return img, nil
+}
+
+// Manifest returns the image manifest for ref, e.g. r8.im/user/model:v1
+func Manifest(ctx context.Context, ref string) (*v1.Manifest, error) {
+	img, err := Image(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get manifest for %s: %w", ref, wrapError(err))
+	}
+	return manifest, nil
+}
+
+// ConfigFile returns the image config for ref, e.g. r8.im/user/model:v1
+func ConfigFile(ctx context.Context, ref string) (*v1.ConfigFile, error) {
+	img, err := Image(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get config for %s: %w", ref, wrapError(err))
+	}
+	return config, nil
+}
+
+// Digest returns the digest of the manifest ref points to, e.g. sha256:... It doesn't use
+// Mirrors, because it's used to find out what was just pushed.
+func Digest(ctx context.Context, ref string) (string, error) {
+	reference, err := name.ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("Invalid reference %s: %w", ref, err)
+	}
+	desc, err := remote.Head(reference, options(ctx)...)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get %s: %w", ref, wrapError(err))
+	}
+	return desc.Digest.String(), nil
+}
+
+// remoteImage returns the image for reference from the first mirror that has it, or from
+// its own registry
+func remoteImage(ctx context.Context, reference name.Reference) (v1.Image, error) {
+	for _, mirror := range Mirrors {
+		mirrored, err := mirrorReference(reference, mirror)
+		if err != nil {
+			return nil, err
+		}
+		img, err := remote.Image(mirrored, options(ctx)...)
+		if err == nil {
+			return img, nil
+		}
+		if !shouldFallBack(err) {
+			return nil, fmt.Errorf("mirror %s: %w", mirror, err)
+		}
+		console.Debugf("Failed to get %s from mirror %s: %s", reference, mirror, err)
+	}
+	return remote.Image(reference, options(ctx)...)
+}
+
+// shouldFallBack returns true if err from a mirror means trying the next registry, because
+// the mirror is rate limiting us, broken, or unreachable. Other errors, like not found or
+// unauthorized, are returned so that a misconfigured mirror isn't hidden.
+func shouldFallBack(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode == http.StatusTooManyRequests || terr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// mirrorReference returns reference with its registry replaced by mirror
+func mirrorReference(reference name.Reference, mirror string) (name.Reference, error) {
+	separator := ":"
+	if _, ok := reference.(name.Digest); ok {
+		separator = "@"
+	}
+	ref := mirror + "/" + reference.Context().RepositoryStr() + separator + reference.Identifier()
+	mirrored, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid registry mirror %s: %w", mirror, err)
+	}
+	return mirrored, nil
+}
+
+func options(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(keychain),
+	}
+}
+
+// wrapError maps registry HTTP errors onto the errors in this package, so callers can
+// use errors.Is without knowing about the transport.
+func wrapError(err error) error {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return err
+	}
+	switch terr.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	case http.StatusMethodNotAllowed:
+		return fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	return err
+}
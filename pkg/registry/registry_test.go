@@ -0,0 +1,250 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+func startRegistry(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return u.Host
+}
+
+func pushRandomImage(t *testing.T, ref string) {
+	t.Helper()
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+}
+
+func TestListTagsAndDelete(t *testing.T) {
+	ctx := context.Background()
+	host := startRegistry(t)
+	repo := host + "/user/model"
+	pushRandomImage(t, repo+":v1")
+	pushRandomImage(t, repo+":v2")
+
+	tags, err := ListTags(ctx, repo)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"v1", "v2"}, tags)
+
+	require.NoError(t, Delete(ctx, repo+":v1"))
+
+	tags, err = ListTags(ctx, repo)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v2"}, tags)
+}
+
+func TestDigest(t *testing.T) {
+	host := startRegistry(t)
+	ref := host + "/user/model:v1"
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	require.NoError(t, Write(context.Background(), ref, img))
+	expected, err := img.Digest()
+	require.NoError(t, err)
+
+	digest, err := Digest(context.Background(), ref)
+	require.NoError(t, err)
+	require.Equal(t, expected.String(), digest)
+}
+
+func TestWriteUploadsOnlyNewLayers(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	uploads := 0
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/blobs/uploads/") {
+			mu.Lock()
+			uploads++
+			mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	repo := u.Host + "/user/model"
+
+	base, err := random.Image(1024, 5)
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, repo+":v1", base))
+	// 5 layers and the config
+	mu.Lock()
+	require.Equal(t, 6, uploads)
+	mu.Unlock()
+
+	layer, err := random.Layer(1024, types.DockerLayer)
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(base, layer)
+	require.NoError(t, err)
+	mu.Lock()
+	uploads = 0
+	mu.Unlock()
+	require.NoError(t, Write(ctx, repo+":v2", img))
+	// Only the new layer and the new config
+	mu.Lock()
+	require.Equal(t, 2, uploads)
+	mu.Unlock()
+
+	expected, err := img.Manifest()
+	require.NoError(t, err)
+	manifest, err := Manifest(ctx, repo+":v2")
+	require.NoError(t, err)
+	require.Equal(t, expected.Layers, manifest.Layers)
+	for _, desc := range manifest.Layers {
+		digest, err := name.NewDigest(repo + "@" + desc.Digest.String())
+		require.NoError(t, err)
+		l, err := remote.Layer(digest)
+		require.NoError(t, err)
+		size, err := l.Size()
+		require.NoError(t, err)
+		require.Equal(t, desc.Size, size)
+	}
+}
+
+func TestWriteUploadsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/blobs/uploads/") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		// Slow uploads down so concurrent ones overlap
+		time.Sleep(20 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	repo := u.Host + "/user/model"
+	t.Cleanup(func() { PushConcurrency = runtime.NumCPU() })
+
+	for i, concurrency := range []int{1, 3} {
+		PushConcurrency = concurrency
+		mu.Lock()
+		maxInFlight = 0
+		mu.Unlock()
+
+		img, err := random.Image(1024, 6)
+		require.NoError(t, err)
+		require.NoError(t, Write(ctx, fmt.Sprintf("%s:v%d", repo, i), img))
+
+		mu.Lock()
+		require.Equal(t, concurrency, maxInFlight)
+		mu.Unlock()
+	}
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	host := startRegistry(t)
+	pushRandomImage(t, host+"/user/model:v1")
+
+	err := Delete(context.Background(), host+"/user/model:missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListTagsNotFound(t *testing.T) {
+	host := startRegistry(t)
+
+	_, err := ListTags(context.Background(), host+"/user/missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMirrors(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	requests := map[string]int{}
+	// count counts the requests to a registry, and fails them all if it's unhealthy
+	count := func(host string, healthy bool, handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[host]++
+			mu.Unlock()
+			if !healthy {
+				http.Error(w, "rate limited", http.StatusTooManyRequests)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+	startServer := func(healthy bool) string {
+		server := httptest.NewUnstartedServer(nil)
+		server.Config.Handler = count(server.Listener.Addr().String(), healthy, registry.New())
+		server.Start()
+		t.Cleanup(server.Close)
+		return server.Listener.Addr().String()
+	}
+	primary := startServer(false)
+	brokenMirror := startServer(false)
+	mirror := startServer(true)
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, mirror+"/user/model:v1", img))
+	expected, err := img.Manifest()
+	require.NoError(t, err)
+
+	Mirrors = []string{brokenMirror, mirror}
+	t.Cleanup(func() { Mirrors = nil })
+
+	manifest, err := Manifest(ctx, primary+"/user/model:v1")
+	require.NoError(t, err)
+	require.Equal(t, expected, manifest)
+	require.Zero(t, requests[primary])
+	require.NotZero(t, requests[brokenMirror])
+
+	// A mirror that doesn't have the image is an error, rather than falling back
+	emptyMirror := startServer(true)
+	Mirrors = []string{emptyMirror}
+	requests = map[string]int{}
+	_, err = Manifest(ctx, primary+"/user/model:v1")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Zero(t, requests[primary])
+
+	// An unreachable mirror falls back to the next one
+	Mirrors = []string{"127.0.0.1:1", mirror}
+	manifest, err = Manifest(ctx, primary+"/user/model:v1")
+	require.NoError(t, err)
+	require.Equal(t, expected, manifest)
+
+	// Pushes never go to mirrors
+	requests = map[string]int{}
+	err = Write(ctx, primary+"/user/model:v2", img)
+	require.Error(t, err)
+	require.NotZero(t, requests[primary])
+	require.Zero(t, requests[mirror])
+}
@@ -0,0 +1,39 @@
+package console
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// Values for the --color flag
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// colorEnabled decides whether to write colors to f. In auto mode, that's when f is a
+// terminal and NO_COLOR isn't set (https://no-color.org/), so logs and pipes don't fill up
+// with escape sequences.
+func colorEnabled(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+		return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && IsTTY(f), nil
+	}
+	return false, fmt.Errorf("Invalid color setting %q. It must be %s, %s, or %s", mode, ColorAuto, ColorAlways, ColorNever)
+}
+
+// colorize is the only place colors are added to output, so nothing is colored when
+// colors are disabled
+func colorize(enabled bool, s string, color func(arg interface{}) aurora.Value) string {
+	if !enabled {
+		return s
+	}
+	return color(s).String()
+}
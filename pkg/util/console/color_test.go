@@ -0,0 +1,54 @@
+package console
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorEnabled(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+
+	// Pipes aren't terminals
+	color, err := colorEnabled(ColorAuto, w)
+	require.NoError(t, err)
+	require.False(t, color)
+
+	color, err = colorEnabled(ColorAlways, w)
+	require.NoError(t, err)
+	require.True(t, color)
+
+	t.Setenv("NO_COLOR", "1")
+	color, err = colorEnabled(ColorAuto, w)
+	require.NoError(t, err)
+	require.False(t, color)
+
+	color, err = colorEnabled(ColorNever, w)
+	require.NoError(t, err)
+	require.False(t, color)
+
+	_, err = colorEnabled("sometimes", w)
+	require.ErrorContains(t, err, `Invalid color setting "sometimes"`)
+}
+
+func TestNoEscapeSequencesWithoutColor(t *testing.T) {
+	out := new(bytes.Buffer)
+	c := &Console{Color: false, Level: DebugLevel, stderr: out}
+	c.Debug("debug")
+	c.Warn("warning")
+	c.Error("error")
+	require.Equal(t, "debug\nwarning\nerror\n", out.String())
+	require.Equal(t, "✓ ", resultMark(true, false))
+
+	out.Reset()
+	c.Color = true
+	c.Warn("warning")
+	require.Contains(t, out.String(), "\033[")
+}
@@ -3,6 +3,7 @@ package console
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -20,6 +21,8 @@ type Console struct {
 	IsMachine bool
 	Level     Level
 	mu        sync.Mutex
+	// stderr is where messages are written, os.Stderr if it's nil
+	stderr io.Writer
 }
 
 // Debug prints a verbose debugging message, that is not displayed by default to the user.
@@ -93,20 +96,24 @@ func (c *Console) log(level Level, msg string) {
 	if c.Color {
 		switch level {
 		case WarnLevel:
-			prompt = aurora.Yellow("⚠ ").String()
+			prompt = colorize(c.Color, "⚠ ", aurora.Yellow)
 		case ErrorLevel, FatalLevel:
-			prompt = aurora.Red("ⅹ ").String()
+			prompt = colorize(c.Color, "ⅹ ", aurora.Red)
 		}
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	stderr := c.stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
 	for _, line := range strings.Split(formattedMsg, "\n") {
-		if c.Color && level == DebugLevel {
-			line = aurora.Faint(line).String()
+		if level == DebugLevel {
+			line = colorize(c.Color, line, aurora.Faint)
 		}
 		line = prompt + line
-		fmt.Fprintln(os.Stderr, line)
+		fmt.Fprintln(stderr, line)
 	}
 }
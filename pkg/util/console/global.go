@@ -8,11 +8,16 @@ import (
 
 // ConsoleInstance is the global instance of console, so we don't have to pass it around everywhere
 var ConsoleInstance = &Console{
-	Color:     true,
+	Color:     autoColor(),
 	Level:     InfoLevel,
 	IsMachine: false,
 }
 
+func autoColor() bool {
+	color, _ := colorEnabled(ColorAuto, os.Stderr)
+	return color
+}
+
 // SetLevel sets log level
 func SetLevel(level Level) {
 	ConsoleInstance.Level = level
@@ -23,6 +28,16 @@ func SetColor(color bool) {
 	ConsoleInstance.Color = color
 }
 
+// SetColorMode sets whether to print colors from the --color flag: auto, always, or never
+func SetColorMode(mode string) error {
+	color, err := colorEnabled(mode, os.Stderr)
+	if err != nil {
+		return err
+	}
+	SetColor(color)
+	return nil
+}
+
 // Debug level message.
 func Debug(msg string) {
 	ConsoleInstance.Debug(msg)
@@ -78,6 +93,16 @@ func Output(s string) {
 	ConsoleInstance.Output(s)
 }
 
+// NewProgressBar returns a progress bar for an operation with a known total
+func NewProgressBar(total int64) ProgressBar {
+	return ConsoleInstance.NewProgressBar(total)
+}
+
+// NewSpinner returns a spinner for an operation with an unknown length
+func NewSpinner(label string) Spinner {
+	return ConsoleInstance.NewSpinner(label)
+}
+
 // IsTTY checks if a file is a TTY or not. E.g. IsTTY(os.Stdin)
 func IsTTY(f *os.File) bool {
 	return isatty.IsTerminal(f.Fd())
@@ -0,0 +1,199 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// ProgressBar shows how far through a long operation we are, e.g. uploading a layer
+type ProgressBar interface {
+	// Update sets how much has been done, out of the total the bar was created with
+	Update(current int64)
+	// Finish marks the operation as done and moves on to the next line
+	Finish()
+	// Fail marks the operation as failed and moves on to the next line
+	Fail()
+}
+
+// Spinner shows that something is happening when we don't know how long it'll take
+type Spinner interface {
+	// Update changes what the spinner says is happening
+	Update(label string)
+	// Finish marks the operation as done and moves on to the next line
+	Finish()
+	// Fail marks the operation as failed and moves on to the next line
+	Fail()
+}
+
+const (
+	progressBarWidth = 40
+	spinnerInterval  = 100 * time.Millisecond
+	clearLine        = "\r\033[K"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// NewProgressBar returns a progress bar written to stderr, or one that does nothing if
+// stderr isn't a terminal that can be redrawn
+func (c *Console) NewProgressBar(total int64) ProgressBar {
+	if !c.isInteractive() {
+		return noopProgressBar{}
+	}
+	return &progressBar{out: os.Stderr, mu: &c.mu, color: c.Color, total: total, percent: -1}
+}
+
+// NewSpinner returns a spinner written to stderr. If stderr isn't a terminal that can be
+// redrawn, the label is logged once at info level instead.
+func (c *Console) NewSpinner(label string) Spinner {
+	if !c.isInteractive() {
+		c.Info(label)
+		return noopSpinner{}
+	}
+	s := &spinner{out: os.Stderr, mu: &c.mu, color: c.Color, label: label, done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+// isInteractive returns true if progress should be shown and can be redrawn in place. It
+// isn't shown with --quiet, and can't be redrawn in logs, pipes, and dumb terminals.
+func (c *Console) isInteractive() bool {
+	return c.Level <= InfoLevel && !c.IsMachine && IsTTY(os.Stderr) && os.Getenv("TERM") != "dumb"
+}
+
+type noopProgressBar struct{}
+
+func (noopProgressBar) Update(int64) {}
+func (noopProgressBar) Finish()      {}
+func (noopProgressBar) Fail()        {}
+
+type noopSpinner struct{}
+
+func (noopSpinner) Update(string) {}
+func (noopSpinner) Finish()       {}
+func (noopSpinner) Fail()         {}
+
+type progressBar struct {
+	out     io.Writer
+	mu      *sync.Mutex
+	color   bool
+	total   int64
+	percent int
+	done    bool
+}
+
+func (p *progressBar) Update(current int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+	percent := 100
+	if p.total > 0 {
+		percent = int(min(max(current, 0), p.total) * 100 / p.total)
+	}
+	// Only redraw when something visible has changed, updates can be very frequent
+	if percent == p.percent {
+		return
+	}
+	p.percent = percent
+	fmt.Fprint(p.out, clearLine+renderProgressBar(percent, progressBarWidth))
+}
+
+func (p *progressBar) Finish() {
+	p.finish(true)
+}
+
+func (p *progressBar) Fail() {
+	p.finish(false)
+}
+
+func (p *progressBar) finish(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+	p.done = true
+	percent := p.percent
+	if ok {
+		percent = 100
+	}
+	fmt.Fprintln(p.out, clearLine+resultMark(ok, p.color)+renderProgressBar(max(percent, 0), progressBarWidth))
+}
+
+func renderProgressBar(percent int, width int) string {
+	filled := percent * width / 100
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), percent)
+}
+
+type spinner struct {
+	out   io.Writer
+	mu    *sync.Mutex
+	color bool
+	label string
+	frame int
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (s *spinner) run() {
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	s.draw()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.draw()
+		}
+	}
+}
+
+func (s *spinner) draw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	fmt.Fprint(s.out, clearLine+spinnerFrames[s.frame%len(spinnerFrames)]+" "+s.label)
+	s.frame++
+}
+
+func (s *spinner) Update(label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.label = label
+}
+
+func (s *spinner) Finish() {
+	s.finish(true)
+}
+
+func (s *spinner) Fail() {
+	s.finish(false)
+}
+
+func (s *spinner) finish(ok bool) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.done)
+		fmt.Fprintln(s.out, clearLine+resultMark(ok, s.color)+s.label)
+	})
+}
+
+func resultMark(ok bool, color bool) string {
+	if ok {
+		return colorize(color, "✓ ", aurora.Green)
+	}
+	return colorize(color, "ⅹ ", aurora.Red)
+}
@@ -0,0 +1,47 @@
+package console
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderProgressBar(t *testing.T) {
+	require.Equal(t, "[          ]   0%", renderProgressBar(0, 10))
+	require.Equal(t, "[====      ]  45%", renderProgressBar(45, 10))
+	require.Equal(t, "[==========] 100%", renderProgressBar(100, 10))
+}
+
+func TestProgressBarOnlyRedrawsOnChange(t *testing.T) {
+	out := new(bytes.Buffer)
+	bar := &progressBar{out: out, mu: new(sync.Mutex), total: 1000, percent: -1}
+	bar.Update(10)
+	bar.Update(11)
+	bar.Update(2000)
+	bar.Finish()
+	bar.Update(500)
+	bar.Fail()
+
+	require.Equal(t, clearLine+renderProgressBar(1, progressBarWidth)+
+		clearLine+renderProgressBar(100, progressBarWidth)+
+		clearLine+"✓ "+renderProgressBar(100, progressBarWidth)+"\n", out.String())
+}
+
+func TestNonInteractiveProgressDoesNothing(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	c := &Console{IsMachine: true, Level: InfoLevel, stderr: stderr}
+	require.Equal(t, noopProgressBar{}, c.NewProgressBar(10))
+	require.Equal(t, noopSpinner{}, c.NewSpinner("Pushing"))
+	// The spinner's label is still logged, so logs say what was happening
+	require.Equal(t, "Pushing\n", stderr.String())
+}
+
+func TestQuietProgressDoesNothing(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	c := &Console{Level: WarnLevel, stderr: stderr}
+	require.Equal(t, noopProgressBar{}, c.NewProgressBar(10))
+	require.Equal(t, noopSpinner{}, c.NewSpinner("Pushing"))
+	require.Empty(t, stderr.String())
+}
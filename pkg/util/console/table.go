@@ -0,0 +1,128 @@
+package console
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/moby/term"
+)
+
+const (
+	tableColumnGap      = "  "
+	tableMinColumnWidth = 5
+)
+
+// Table writes rows to stdout in columns under headers. When stdout isn't a terminal, it
+// writes tab-separated values instead so the output is easy to process. Commands with a
+// --json flag should write JSON instead of calling this.
+func (c *Console) Table(headers []string, rows [][]string) {
+	width := 0
+	if !c.IsMachine {
+		width = stdoutWidth()
+	}
+	c.Output(renderTable(headers, rows, width))
+}
+
+// Table writes rows to stdout in columns under headers
+func Table(headers []string, rows [][]string) {
+	ConsoleInstance.Table(headers, rows)
+}
+
+// stdoutWidth returns the width of the terminal on stdout, or 0 if it's not a terminal
+func stdoutWidth() int {
+	fd := os.Stdout.Fd()
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	ws, err := term.GetWinsize(fd)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Width)
+}
+
+// renderTable lays out a table that fits in width, truncating the widest columns if it
+// has to. A width of 0 means tab-separated values.
+func renderTable(headers []string, rows [][]string, width int) string {
+	if width == 0 {
+		lines := []string{tsvLine(headers)}
+		for _, row := range rows {
+			lines = append(lines, tsvLine(row))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	widths := tableColumnWidths(headers, rows, width)
+	lines := []string{tableLine(headers, widths)}
+	for _, row := range rows {
+		lines = append(lines, tableLine(row, widths))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableColumnWidths returns the width of each column, so the table fits in width where
+// possible without making any column narrower than tableMinColumnWidth
+func tableColumnWidths(headers []string, rows [][]string, width int) []int {
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+			}
+		}
+	}
+
+	available := width - len(tableColumnGap)*(len(widths)-1)
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	for total > available {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= tableMinColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+func tableLine(cells []string, widths []int) string {
+	parts := make([]string, len(widths))
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = truncate(strings.Join(strings.Fields(cells[i]), " "), w)
+		}
+		if i < len(widths)-1 {
+			cell += strings.Repeat(" ", w-utf8.RuneCountInString(cell))
+		}
+		parts[i] = cell
+	}
+	return strings.Join(parts, tableColumnGap)
+}
+
+// truncate shortens s to width characters, ending with … if it was cut
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+func tsvLine(cells []string) string {
+	replacer := strings.NewReplacer("\t", " ", "\n", " ")
+	parts := make([]string, len(cells))
+	for i, cell := range cells {
+		parts[i] = replacer.Replace(cell)
+	}
+	return strings.Join(parts, "\t")
+}
@@ -0,0 +1,39 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var tableHeaders = []string{"NAME", "TAG", "DESCRIPTION"}
+
+var tableRows = [][]string{
+	{"hello-world", "latest", "Says hello"},
+	{"resnet", "v2", "Classifies images into one of a thousand categories"},
+}
+
+func TestTableColumnWidths(t *testing.T) {
+	// Wide enough for everything
+	require.Equal(t, []int{11, 6, 51}, tableColumnWidths(tableHeaders, tableRows, 100))
+
+	// The widest column is truncated first
+	require.Equal(t, []int{11, 6, 29}, tableColumnWidths(tableHeaders, tableRows, 50))
+
+	// Columns never get narrower than the minimum, even if the table doesn't fit
+	require.Equal(t, []int{5, 5, 5}, tableColumnWidths(tableHeaders, tableRows, 10))
+}
+
+func TestRenderTable(t *testing.T) {
+	require.Equal(t, `NAME         TAG     DESCRIPTION
+hello-world  latest  Says hello
+resnet       v2      Classifies images into one o…`, renderTable(tableHeaders, tableRows, 50))
+}
+
+func TestRenderTableNotATerminal(t *testing.T) {
+	rows := append(tableRows, []string{"multi\tline", "v1", "First\nsecond"})
+	require.Equal(t, "NAME\tTAG\tDESCRIPTION\n"+
+		"hello-world\tlatest\tSays hello\n"+
+		"resnet\tv2\tClassifies images into one of a thousand categories\n"+
+		"multi line\tv1\tFirst second", renderTable(tableHeaders, rows, 0))
+}
@@ -4,11 +4,15 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"sync"
 )
 
 // Manifest contains metadata about weights files in a model
@@ -78,30 +82,123 @@ func (m *Manifest) Equal(other *Manifest) bool {
 
 // AddFile adds a file to the manifest, calculating its CRC32 checksum
 func (m *Manifest) AddFile(path string) error {
+	checksum, err := checksumFile(path)
+	if err != nil {
+		return err
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]Metadata)
+	}
+	m.Files[path] = Metadata{
+		CRC32: checksum,
+	}
+	return nil
+}
+
+// AddFiles adds files to the manifest like AddFile, checksumming up to concurrency
+// files at a time. Weights are often several large files, so this is much faster than
+// adding them one by one. If any file fails, the error for the first one in paths is
+// returned and the manifest is unchanged.
+func (m *Manifest) AddFiles(paths []string, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	checksums := make([]string, len(paths))
+	errs := make([]error, len(paths))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				checksums[i], errs[i] = checksumFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]Metadata)
+	}
+	for i, path := range paths {
+		m.Files[path] = Metadata{
+			CRC32: checksums[i],
+		}
+	}
+	return nil
+}
+
+// Mismatch is a file in a manifest that doesn't match what's on disk
+type Mismatch struct {
+	Path string
+	// Expected is the CRC32 checksum in the manifest
+	Expected string
+	// Actual is the CRC32 checksum of the file on disk, or empty if it's missing
+	Actual  string
+	Missing bool
+}
+
+// Verify checks the files in the manifest against the files on disk, relative to baseDir,
+// and returns the ones that are missing or have a different checksum, sorted by path. It
+// checks every file rather than stopping at the first mismatch. Files that can't be read
+// for other reasons are returned as an error after the rest have been checked.
+func (m *Manifest) Verify(baseDir string) ([]Mismatch, error) {
+	paths := make([]string, 0, len(m.Files))
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	mismatches := []Mismatch{}
+	errs := []error{}
+	for _, path := range paths {
+		expected := m.Files[path].CRC32
+		fullPath := path
+		if !filepath.IsAbs(path) {
+			fullPath = filepath.Join(baseDir, path)
+		}
+		actual, err := checksumFile(fullPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			mismatches = append(mismatches, Mismatch{Path: path, Expected: expected, Missing: true})
+		case err != nil:
+			errs = append(errs, err)
+		case actual != expected:
+			mismatches = append(mismatches, Mismatch{Path: path, Expected: expected, Actual: actual})
+		}
+	}
+	return mismatches, errors.Join(errs...)
+}
+
+// checksumFile returns the CRC32 checksum of the file at path, encoded as a hexadecimal
+// string. The file is streamed, so it's never all in memory at once.
+func checksumFile(path string) (string, error) {
 	crc32Algo := crc32.NewIEEE()
 	// generate checksum of file
 	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", path, err)
+		return "", fmt.Errorf("failed to open file %s: %w", path, err)
 	}
 	defer file.Close()
 	_, err = io.Copy(crc32Algo, file)
 	if err != nil {
-		return fmt.Errorf("failed to generate checksum of file %s: %w", path, err)
+		return "", fmt.Errorf("failed to generate checksum of file %s: %w", path, err)
 	}
 	checksum := crc32Algo.Sum32()
 
 	// encode checksum as hexadecimal string
 	bytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(bytes, checksum)
-	encoded := hex.EncodeToString(bytes)
-
-	if m.Files == nil {
-		m.Files = make(map[string]Metadata)
-	}
-	m.Files[path] = Metadata{
-		CRC32: encoded,
-	}
-
-	return nil
+	return hex.EncodeToString(bytes), nil
 }
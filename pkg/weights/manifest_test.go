@@ -0,0 +1,78 @@
+package weights
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeWeights(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	paths := []string{}
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("model-%d.safetensors", i))
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("weights %d", i)), 0o644))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestAddFilesMatchesAddFile(t *testing.T) {
+	paths := writeWeights(t, t.TempDir(), 10)
+
+	expected := NewManifest()
+	for _, path := range paths {
+		require.NoError(t, expected.AddFile(path))
+	}
+
+	for _, concurrency := range []int{0, 1, 4, 20} {
+		m := NewManifest()
+		require.NoError(t, m.AddFiles(paths, concurrency))
+		require.Equal(t, expected.Files, m.Files, "concurrency %d", concurrency)
+	}
+}
+
+func TestAddFilesMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeWeights(t, dir, 3)
+	paths = append(paths, filepath.Join(dir, "missing.bin"))
+
+	m := NewManifest()
+	err := m.AddFiles(paths, 2)
+	require.ErrorContains(t, err, "missing.bin")
+	require.Empty(t, m.Files)
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeWeights(t, dir, 3)
+
+	// Manifests have paths relative to the project directory
+	m := NewManifest()
+	require.NoError(t, m.AddFiles(paths, 2))
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		require.NoError(t, err)
+		m.Files[rel] = m.Files[path]
+		delete(m.Files, path)
+	}
+
+	mismatches, err := m.Verify(dir)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model-1.safetensors"), []byte("corrupted"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "model-2.safetensors")))
+
+	mismatches, err = m.Verify(dir)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 2)
+	require.Equal(t, "model-1.safetensors", mismatches[0].Path)
+	require.Equal(t, m.Files["model-1.safetensors"].CRC32, mismatches[0].Expected)
+	require.NotEqual(t, mismatches[0].Expected, mismatches[0].Actual)
+	require.False(t, mismatches[0].Missing)
+	require.Equal(t, Mismatch{Path: "model-2.safetensors", Expected: m.Files["model-2.safetensors"].CRC32, Missing: true}, mismatches[1])
+}
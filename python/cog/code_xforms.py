@@ -1,7 +1,7 @@
 import ast
 import re
 import types
-from typing import Optional, Set, Union
+from typing import List, Optional, Set, Union
 
 COG_IMPORT_MODULES = {"cog", "typing", "sys", "os", "functools", "pydantic", "numpy"}
 
@@ -67,6 +67,17 @@ def extract_function_source(source_code: str, function_name: str) -> str:
     return extractor.function_source if extractor.function_source else ""
 
 
+def empty_body(node: ast.FunctionDef) -> List[ast.stmt]:
+    """
+    Returns a function body that just returns None, keeping the docstring so it can
+    still be used as the description in the schema.
+    """
+    body: List[ast.stmt] = [ast.Return(value=ast.Constant(value=None))]
+    if ast.get_docstring(node) is not None:
+        body.insert(0, node.body[0])
+    return body
+
+
 def make_class_methods_empty(source_code: Union[str, ast.AST], class_name: str) -> str:
     """
     Transforms the source code of a specified class to remove the bodies of all its methods
@@ -84,7 +95,7 @@ def make_class_methods_empty(source_code: Union[str, ast.AST], class_name: str)
                 for body_item in node.body:
                     if isinstance(body_item, ast.FunctionDef):
                         # Replace the body of the method with `return None`
-                        body_item.body = [ast.Return(value=ast.Constant(value=None))]
+                        body_item.body = empty_body(body_item)
                 return node
 
     tree = source_code if isinstance(source_code, ast.AST) else ast.parse(source_code)
@@ -169,7 +180,7 @@ def make_function_empty(source_code: Union[str, ast.AST], function_name: str) ->
         def visit_FunctionDef(self, node: ast.FunctionDef) -> Optional[ast.AST]:
             if node.name == function_name:
                 # Replace the body of the function with `return None`
-                node.body = [ast.Return(value=ast.Constant(value=None))]
+                node.body = empty_body(node)
                 return node
 
     tree = source_code if isinstance(source_code, ast.AST) else ast.parse(source_code)
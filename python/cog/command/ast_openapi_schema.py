@@ -1,6 +1,10 @@
 import ast
+import collections
+import io
 import json
+import re
 import sys
+import tokenize
 import types
 import typing
 from pathlib import Path
@@ -302,17 +306,132 @@ OPENAPI_TYPES = {
     "cog.File": "string",
     "Path": "string",
     "File": "string",
+    "datetime": "string",
+    "date": "string",
 }
 
+# The string formats of types that are represented as strings
+OPENAPI_FORMATS = {
+    "Path": "uri",
+    "File": "uri",
+    "datetime": "date-time",
+    "date": "date",
+}
+
+
+def get_format(name: str) -> "JSONDict":
+    """Return the string format of a type, like date-time for datetime"""
+    return {"format": OPENAPI_FORMATS[name]} if name in OPENAPI_FORMATS else {}
+
+
+class ChoicesNotResolvable(ValueError):
+    """Raised when choices refer to a sidecar file that can't be read."""
+
+
+CHOICES_FILE_PREFIX = "@"
+
+
+def is_choices_file_ref(choices: object) -> bool:
+    return isinstance(choices, str) and choices.startswith(CHOICES_FILE_PREFIX)
+
+
+def resolve_choices(ref: str, base_dir: "str | Path") -> "list[typing.Any]":
+    """
+    Resolve choices of the form "@choices.json:key" (or "@choices.json" for a
+    top-level list) by reading the file relative to base_dir. YAML files are
+    supported too.
+    """
+    filename, _, key = ref[len(CHOICES_FILE_PREFIX) :].partition(":")
+    path = Path(base_dir) / filename
+    try:
+        contents = path.read_text(encoding="utf-8")
+    except OSError as e:
+        raise ChoicesNotResolvable(
+            f"Could not read choices file {filename}: {e.strerror}"
+        ) from e
+
+    try:
+        if path.suffix in (".yaml", ".yml"):
+            import yaml
+
+            data = yaml.safe_load(contents)
+        else:
+            data = json.loads(contents)
+    except ValueError as e:
+        raise ChoicesNotResolvable(
+            f"Could not parse choices file {filename}: {e}"
+        ) from e
+
+    if key:
+        if not isinstance(data, dict) or key not in data:
+            raise ChoicesNotResolvable(
+                f"Choices file {filename} does not contain the key {key!r}"
+            )
+        data = data[key]
+    if not isinstance(data, list) or not data:
+        raise ChoicesNotResolvable(
+            f"Choices in {ref} must be a non-empty list of strings or integers"
+        )
+    return data
+
 
 def find(obj: ast.AST, name: str) -> ast.AST:
     """Find a particular named node in a tree"""
     return next(node for node in ast.walk(obj) if getattr(node, "name", "") == name)
 
 
+FunctionNode = (ast.FunctionDef, ast.AsyncFunctionDef)
+
+
+def find_method(
+    tree: ast.Module, fn: str, class_name: str
+) -> "ast.FunctionDef | ast.AsyncFunctionDef":
+    """
+    Find the fn method of class_name, following base classes defined in the same
+    file in method resolution order. Mixins may define methods with the same
+    name, so this can't just be the first function called fn in the file.
+    For function predictors, class_name is the name of the function, like in
+    predict.py:predict.
+    """
+    classes = {
+        node.name: node for node in tree.body if isinstance(node, ast.ClassDef)
+    }
+    if class_name not in classes:
+        for node in tree.body:
+            if isinstance(node, FunctionNode) and node.name == class_name:
+                return node
+        raise ValueError(f"Could not find a class or function called {class_name}")
+
+    missing_bases: "list[str]" = []
+
+    def search(
+        classdef: ast.ClassDef,
+    ) -> "ast.FunctionDef | ast.AsyncFunctionDef | None":
+        for node in classdef.body:
+            if isinstance(node, FunctionNode) and node.name == fn:
+                return node
+        for base in classdef.bases:
+            base_name = resolve_name(base)
+            if base_name in classes:
+                found = search(classes[base_name])
+                if found:
+                    return found
+            elif base_name.split(".")[-1] != "BasePredictor":
+                missing_bases.append(base_name)
+        return None
+
+    found = search(classes[class_name])
+    if found is None:
+        msg = f"Could not find {fn}() on {class_name}"
+        if missing_bases:
+            msg += f". It may be inherited from {', '.join(missing_bases)}, which must be defined in the same file"
+        raise ValueError(msg)
+    return found
+
+
 if typing.TYPE_CHECKING:
     AstVal: "typing.TypeAlias" = (
-        "int | float | complex | str | list[AstVal] | bytes | None"
+        "int | float | complex | str | list[AstVal] | dict[typing.Any, AstVal] | bytes | None"
     )
     AstValNoBytes: "typing.TypeAlias" = "int | float | str | list[AstValNoBytes]"
     JSONObject: "typing.TypeAlias" = (
@@ -326,6 +445,8 @@ def to_serializable(val: "AstVal") -> "JSONObject":
         return val.decode("utf-8")
     elif isinstance(val, list):
         return [to_serializable(x) for x in val]
+    elif isinstance(val, dict):
+        return {to_serializable(k): to_serializable(v) for k, v in val.items()}  # type: ignore
     elif isinstance(val, complex):
         msg = "complex inputs are not supported"
         raise ValueError(msg)
@@ -333,8 +454,74 @@ def to_serializable(val: "AstVal") -> "JSONObject":
         return val
 
 
-def get_value(node: ast.AST) -> "AstVal":
-    """Return the value of constant or list of constants"""
+BINARY_OPERATORS: "dict[type, typing.Callable[..., typing.Any]]" = {
+    ast.Add: lambda a, b: a + b,
+    ast.Sub: lambda a, b: a - b,
+    ast.Mult: lambda a, b: a * b,
+    ast.Div: lambda a, b: a / b,
+}
+
+
+ITERABLE_CALLS: "dict[str, typing.Callable[..., typing.Any]]" = {
+    "list": list,
+    "tuple": list,
+    "sorted": sorted,
+}
+DICT_METHODS = ("keys", "values")
+
+
+def get_iterable(
+    node: ast.AST, scope: "dict[str, AstVal] | None"
+) -> "typing.Iterable[AstVal]":
+    """Return the values of dict.keys(), dict.values(), or a dict or list constant"""
+    if (
+        isinstance(node, ast.Call)
+        and isinstance(node.func, ast.Attribute)
+        and node.func.attr in DICT_METHODS
+        and not node.args
+    ):
+        value = get_value(node.func.value, scope)
+        if not isinstance(value, dict):
+            raise ValueError("Unexpected node type", type(node), ast.unparse(node))
+        return getattr(value, node.func.attr)()
+    value = get_value(node, scope)
+    if not isinstance(value, (dict, list)):
+        raise ValueError("Unexpected node type", type(node), ast.unparse(node))
+    return value
+
+
+def dotted_name(node: ast.AST) -> "str | None":
+    """Return the name of an attribute of a name, like pkg.constants.STEPS"""
+    if isinstance(node, ast.Name):
+        return node.id
+    if isinstance(node, ast.Attribute):
+        value = dotted_name(node.value)
+        return f"{value}.{node.attr}" if value is not None else None
+    return None
+
+
+def get_value(node: ast.AST, scope: "dict[str, AstVal] | None" = None) -> "AstVal":
+    """
+    Return the value of constant or list of constants. Names are looked up in
+    scope, and simple arithmetic over numeric constants is evaluated.
+    """
+    if isinstance(node, ast.Name) and scope is not None and node.id in scope:
+        return scope[node.id]
+    if isinstance(node, ast.Attribute) and scope is not None:
+        # constants of imported local modules, like constants.DEFAULT_STEPS or
+        # pkg.constants.DEFAULT_STEPS
+        name = dotted_name(node)
+        if name in scope:
+            return scope[name]
+    if isinstance(node, ast.BinOp) and type(node.op) in BINARY_OPERATORS:
+        left = get_value(node.left, scope)
+        right = get_value(node.right, scope)
+        if not all(
+            isinstance(v, (int, float)) and not isinstance(v, bool)
+            for v in (left, right)
+        ):
+            raise ValueError("Unexpected node type", type(node), ast.unparse(node))
+        return BINARY_OPERATORS[type(node.op)](left, right)
     if isinstance(node, ast.Constant):
         return node.value
     # for python3.7, were deprecated for Constant
@@ -343,18 +530,125 @@ def get_value(node: ast.AST) -> "AstVal":
     if isinstance(node, ast.Num):
         return node.n
     if isinstance(node, (ast.List, ast.Tuple)):
-        return [get_value(e) for e in node.elts]
+        return [get_value(e, scope) for e in node.elts]
+    if isinstance(node, ast.Dict):
+        if any(k is None for k in node.keys):
+            # {**BASE, "x": "X"}
+            raise ValueError("Dict unpacking is not supported", ast.unparse(node))
+        return {
+            get_value(k, scope): get_value(v, scope)
+            for k, v in zip(node.keys, node.values)
+            if k is not None
+        }
+    if (
+        isinstance(node, ast.Call)
+        and isinstance(node.func, ast.Name)
+        and node.func.id in ITERABLE_CALLS
+        and len(node.args) == 1
+        and not node.keywords
+    ):
+        # e.g. list(RATIOS.keys()), which is commonly used for choices
+        return ITERABLE_CALLS[node.func.id](get_iterable(node.args[0], scope))
     if isinstance(node, ast.UnaryOp) and isinstance(node.op, ast.USub):
-        return -typing.cast(typing.Union[int, float, complex], get_value(node.operand))
+        return -typing.cast(
+            typing.Union[int, float, complex], get_value(node.operand, scope)
+        )
     raise ValueError("Unexpected node type", type(node))
 
 
+def collect_module_scope(
+    tree: ast.Module,
+    base_dir: "str | Path | None" = None,
+    _seen: "set[Path] | None" = None,
+    _module_dir: "Path | None" = None,
+) -> "dict[str, AstVal]":
+    """
+    Return the module-level constants that can be resolved, in order, so that
+    constants derived from earlier ones (GUIDANCE = STEPS / 3.0) resolve too.
+    If base_dir is given, constants imported from local modules in it
+    (from constants import DEFAULT_PROMPT, or import constants) are resolved as well.
+    base_dir is the project root, which absolute imports are relative to even in
+    modules in subdirectories. Relative imports are relative to the module's own directory.
+    """
+    scope: "dict[str, AstVal]" = {}
+    for stmt in tree.body:
+        if isinstance(stmt, (ast.Import, ast.ImportFrom)) and base_dir is not None:
+            scope.update(
+                import_local_constants(
+                    stmt, Path(base_dir), _module_dir or Path(base_dir), _seen or set()
+                )
+            )
+            continue
+        if isinstance(stmt, ast.Assign):
+            targets, value = stmt.targets, stmt.value
+        elif isinstance(stmt, ast.AnnAssign) and stmt.value is not None:
+            targets, value = [stmt.target], stmt.value
+        else:
+            continue
+        if len(targets) != 1 or not isinstance(targets[0], ast.Name):
+            continue
+        try:
+            scope[targets[0].id] = get_value(value, scope)
+        except (ValueError, ArithmeticError):
+            # not a constant, so it can't be used as a default
+            scope.pop(targets[0].id, None)
+    return scope
+
+
+def import_local_constants(
+    stmt: "ast.Import | ast.ImportFrom",
+    base_dir: Path,
+    module_dir: Path,
+    seen: "set[Path]",
+) -> "dict[str, AstVal]":
+    """
+    Return the constants imported from modules in base_dir, by name for from-imports
+    (from constants import STEPS) and by attribute for imports (import constants, then
+    constants.STEPS). Relative imports (from .constants import STEPS) are looked up in
+    module_dir. Imports of other modules, like installed packages, are skipped.
+    """
+    if isinstance(stmt, ast.Import):
+        scope: "dict[str, AstVal]" = {}
+        for alias in stmt.names:
+            module_scope = local_module_scope(alias.name, base_dir, base_dir, seen)
+            for name, value in module_scope.items():
+                scope[f"{alias.asname or alias.name}.{name}"] = value
+        return scope
+    if stmt.module is None or stmt.level > 1:
+        return {}
+    search_dir = module_dir if stmt.level == 1 else base_dir
+    module_scope = local_module_scope(stmt.module, search_dir, base_dir, seen)
+    return {
+        alias.asname or alias.name: module_scope[alias.name]
+        for alias in stmt.names
+        if alias.name in module_scope
+    }
+
+
+def local_module_scope(
+    module: str, search_dir: Path, base_dir: Path, seen: "set[Path]"
+) -> "dict[str, AstVal]":
+    """Return the constants in module, if it's a file in search_dir"""
+    path = (search_dir / Path(*module.split("."))).with_suffix(".py")
+    if not path.is_file() or path.resolve() in seen:
+        return {}
+    try:
+        code = path.read_text(encoding="utf-8")
+    except OSError:
+        return {}
+    return collect_module_scope(
+        ast.parse(code), base_dir, seen | {path.resolve()}, path.parent
+    )
+
+
 def get_annotation(node: "ast.AST | None") -> str:
     """Return the annotation as a string"""
     if isinstance(node, ast.Name):
         return node.id
     if isinstance(node, ast.Constant):
         return node.value  # e.g. arg: "Path"
+    if isinstance(node, ast.Attribute):
+        return node.attr  # e.g. arg: datetime.date
     # ignore Subscript (Optional[str]), BinOp (str | int), and stuff like that
     # except we may need to care about list/List[str]
     raise ValueError("Unexpected annotation type", type(node))
@@ -369,22 +663,156 @@ def get_call_name(call: ast.Call) -> str:
     raise ValueError("Unexpected node type", type(call), ast.unparse(call))
 
 
-def parse_args(tree: ast.AST) -> "list[tuple[ast.arg, ast.expr | types.EllipsisType]]":
+def subscript_slice(node: ast.Subscript) -> ast.expr:
+    """Return what's in the brackets of a subscript, e.g. str for List[str]"""
+    # ast.Index is deprecated, but needed for py3.8
+    return getattr(node.slice, "value", node.slice)
+
+
+def parse_args(
+    tree: ast.Module, class_name: str
+) -> "list[tuple[ast.arg, ast.expr | types.EllipsisType]]":
     """Parse argument, default pairs from a file with a predict function"""
-    predict = find(tree, "predict")
-    assert isinstance(predict, ast.FunctionDef)
+    predict = find_method(tree, "predict", class_name)
     args = predict.args.args  # [-len(defaults) :]
     # use Ellipsis instead of None here to distinguish a default of None
     defaults = [...] * (len(args) - len(predict.args.defaults)) + predict.args.defaults
     return list(zip(args, defaults))
 
 
-def parse_assignment(assignment: ast.AST) -> "None | tuple[str, JSONObject]":
+def unwrap_annotated(
+    annotation: "ast.expr | None", default: "ast.expr | types.EllipsisType"
+) -> "tuple[ast.expr | None, ast.expr | types.EllipsisType]":
+    """
+    Return the type and default of a parameter. For Annotated[T, Input(...)], that's T
+    and the Input() call, with the parameter's own default (if any) passed to it.
+    """
+    if not (
+        isinstance(annotation, ast.Subscript)
+        and resolve_name(annotation.value) == "Annotated"
+    ):
+        return annotation, default
+    slice = subscript_slice(annotation)
+    if not isinstance(slice, ast.Tuple) or not slice.elts:
+        raise ValueError("Unexpected annotation", ast.unparse(annotation))
+    annotation, *metadata = slice.elts
+    for meta in metadata:
+        if isinstance(meta, ast.Call) and get_call_name(meta) == "Input":
+            if default is not ...:
+                keywords = [kw for kw in meta.keywords if kw.arg != "default"]
+                meta = ast.Call(
+                    func=meta.func,
+                    args=meta.args,
+                    keywords=[*keywords, ast.keyword(arg="default", value=default)],
+                )
+            return annotation, meta
+    return annotation, default
+
+
+# pydantic Field() constraints, and the keywords pydantic emits them as
+FIELD_CONSTRAINTS = {
+    "ge": "minimum",
+    "le": "maximum",
+    "min_length": "minLength",
+    "max_length": "maxLength",
+    "regex": "pattern",
+}
+
+
+def is_ellipsis(node: ast.AST) -> bool:
+    return isinstance(node, ast.Constant) and node.value is ...
+
+
+def parse_field_call(call: ast.Call) -> "JSONDict":
+    """Parse the default and constraints of a pydantic Field() call"""
+    kws = {kw.arg: kw.value for kw in call.keywords if kw.arg is not None}
+    if call.args:
+        kws["default"] = call.args[0]
+    field: JSONDict = {}
+    for arg, value in kws.items():
+        if arg == "default":
+            # Field(...) is required, like a field with no default
+            if not is_ellipsis(value):
+                field["default"] = to_serializable(get_value(value))
+        elif arg in FIELD_CONSTRAINTS:
+            field[FIELD_CONSTRAINTS[arg]] = to_serializable(get_value(value))
+    return field
+
+
+def unwrap_literal(
+    name: str, annotation: "ast.expr | None"
+) -> "tuple[ast.expr | None, list[AstVal] | None]":
+    """
+    Return the type and values of a Literal["a", "b"] parameter, which is shorthand for
+    str with choices=["a", "b"]
+    """
+    if not (
+        isinstance(annotation, ast.Subscript)
+        and resolve_name(annotation.value) == "Literal"
+    ):
+        return annotation, None
+    slice = subscript_slice(annotation)
+    elts = slice.elts if isinstance(slice, ast.Tuple) else [slice]
+    values = [get_value(elt) for elt in elts]
+    for type_name in ("str", "int"):
+        if all(type(v).__name__ == type_name for v in values):
+            return ast.Name(id=type_name), values
+    raise ValueError(
+        f"The Literal values for input {name} must all be strings or all be integers"
+    )
+
+
+# Comments for tools, like `# type: ignore` or `# noqa: E501`, which aren't descriptions,
+# either on their own or after a description
+TOOL_COMMENT = re.compile(
+    r"(?:^|\s*#)\s*(?:type:|noqa\b|pylint:|fmt:|mypy:|pyright:|isort:|nosec\b|pragma:).*$"
+)
+
+
+def comment_text(comment: str) -> str:
+    """Return the text of a comment token, without tool comments"""
+    return TOOL_COMMENT.sub("", comment.lstrip("#").strip()).strip()
+
+
+def parse_parameter_comments(code: str, fn: str, class_name: str) -> "dict[str, str]":
+    """
+    Return the trailing comments on the lines of fn's parameters, like
+    `steps: int = 50,  # Number of denoising steps`, which are used as descriptions
+    for inputs that don't have one. Comments on lines with more than one parameter
+    are ignored, because it's not clear which parameter they're about.
+    """
+    method = find_method(ast.parse(code), fn, class_name)
+    comments = {
+        token.start[0]: comment_text(token.string)
+        for token in tokenize.generate_tokens(io.StringIO(code).readline)
+        if token.type == tokenize.COMMENT
+    }
+    args = method.args.args + method.args.kwonlyargs
+    defaults = [None] * (len(method.args.args) - len(method.args.defaults))
+    defaults += method.args.defaults + method.args.kw_defaults
+    end_lines = {
+        arg.arg: max(arg.end_lineno or arg.lineno, getattr(default, "end_lineno", 0))
+        for arg, default in zip(args, defaults)
+    }
+    counts = collections.Counter(end_lines.values())
+    return {
+        name: comments[line]
+        for name, line in end_lines.items()
+        if counts[line] == 1 and comments.get(line)
+    }
+
+
+def parse_assignment(assignment: ast.AST) -> "None | tuple[str, JSONDict]":
     """Parse an assignment into an OpenAPI object property"""
     if isinstance(assignment, ast.AnnAssign):
         assert isinstance(assignment.target, ast.Name)  # shouldn't be an Attribute
-        default = {}
-        if assignment.value:
+        default: JSONDict = {}
+        if (
+            isinstance(assignment.value, ast.Call)
+            and get_call_name(assignment.value) == "Field"
+        ):
+            default = parse_field_call(assignment.value)
+        elif assignment.value:
             try:
                 default = {"default": to_serializable(get_value(assignment.value))}
             except UnicodeDecodeError:
@@ -392,6 +820,7 @@ def parse_assignment(assignment: ast.AST) -> "None | tuple[str, JSONObject]":
         return assignment.target.id, {
             "title": assignment.target.id.replace("_", " ").title(),
             "type": OPENAPI_TYPES[get_annotation(assignment.annotation)],
+            **get_format(get_annotation(assignment.annotation)),
             **default,
         }
     if isinstance(assignment, ast.Assign):
@@ -414,11 +843,15 @@ def parse_class(classdef: ast.AST) -> "JSONDict":
         for assignment in map(parse_assignment, classdef.body)
         if assignment
     }
-    return {
+    schema: JSONDict = {
         "title": classdef.name,
         "type": "object",
         "properties": properties,
     }
+    required = [name for name, prop in properties.items() if "default" not in prop]
+    if required:
+        schema["required"] = required
+    return schema
 
 
 # The supported types are:
@@ -429,7 +862,7 @@ def parse_class(classdef: ast.AST) -> "JSONDict":
 # cog.File: a file-like object representing a file
 # cog.Path: a path to a file on disk
 
-BASE_TYPES = ["str", "int", "float", "bool", "File", "Path"]
+BASE_TYPES = ["str", "int", "float", "bool", "File", "Path", "datetime", "date"]
 
 
 def resolve_name(node: ast.expr) -> str:
@@ -445,12 +878,47 @@ def resolve_name(node: ast.expr) -> str:
     raise ValueError("Unexpected node type", type(node), ast.unparse(node))
 
 
+TUPLE_TYPES = ("tuple", "Tuple")
+
+
+def type_schema(node: ast.expr) -> "JSONDict":
+    """Return the schema of an item in a tuple, which doesn't have a title"""
+    name = resolve_name(node)
+    if name in TUPLE_TYPES:
+        return tuple_schema(node)
+    if name in ("list", "List"):
+        if not isinstance(node, ast.Subscript):
+            return {"type": "array", "items": {}}
+        item = subscript_slice(node)
+        return {"type": "array", "items": type_schema(item)}
+    if name in BASE_TYPES:
+        return {"type": OPENAPI_TYPES[name], **get_format(name)}
+    raise ValueError("Unsupported type in tuple", ast.unparse(node))
+
+
+def tuple_schema(node: ast.expr) -> "JSONDict":
+    """
+    Return the schema of a tuple. Like pydantic, tuple[str, float] is an array with an item
+    schema for each position, while tuple[str, ...] and bare tuple are just arrays.
+    """
+    if not isinstance(node, ast.Subscript):
+        return {"type": "array", "items": {}}
+    slice = subscript_slice(node)
+    elts = slice.elts if isinstance(slice, ast.Tuple) else [slice]
+    if len(elts) == 2 and isinstance(elts[1], ast.Constant) and elts[1].value is ...:
+        return {"type": "array", "items": type_schema(elts[0])}
+    return {
+        "type": "array",
+        "minItems": len(elts),
+        "maxItems": len(elts),
+        "items": [type_schema(elt) for elt in elts],
+    }
+
+
 def parse_return_annotation(
-    tree: ast.AST, fn: str = "predict"
+    tree: ast.Module, fn: str, class_name: str
 ) -> "tuple[JSONDict, JSONDict]":
-    predict = find(tree, fn)
-    if not isinstance(predict, ast.FunctionDef):
-        raise ValueError("Could not find predict function")
+    predict = find_method(tree, fn, class_name)
     annotation = predict.returns
     if not annotation:
         raise TypeError(
@@ -470,10 +938,12 @@ For example:
     # attributes should be resolved to names, maybe blindly
     # subscript values are iterator or
     name = resolve_name(annotation)
+    if name in TUPLE_TYPES:
+        return {}, {"title": "Output", **tuple_schema(annotation)}
     if isinstance(annotation, ast.Subscript):
         # forget about other subscripts like Optional, and assume otherlib.File will still be an uri
         slice = resolve_name(annotation.slice)
-        format = {"format": "uri"} if slice in ("Path", "File") else {}
+        format = get_format(slice)
         array_type = {"x-cog-array-type": "iterator"} if "Iterator" in name else {}
         display_type = (
             {"x-cog-array-display": "concatenate"} if "Concatenate" in name else {}
@@ -490,7 +960,7 @@ For example:
         }
     if name in BASE_TYPES:
         # otherwise figure this out...
-        format = {"format": "uri"} if name in ("Path", "File") else {}
+        format = get_format(name)
         return {}, {"title": "Output", "type": OPENAPI_TYPES.get(name, name), **format}
     # it must be a custom object
     schema: JSONDict = {name: parse_class(find(tree, name))}
@@ -500,42 +970,141 @@ For example:
     }
 
 
-KEPT_ATTRS = ("description", "default", "ge", "le", "max_length", "min_length", "regex")
+KEPT_ATTRS = (
+    "title",
+    "description",
+    "default",
+    "example",
+    "examples",
+)
+# Input() arguments that are emitted as vendor extensions
+EXTENSION_ATTRS = {
+    "content_types": "x-cog-content-types",
+    "extensions": "x-cog-extensions",
+}
+
+
+# The Python types an example value can have for each OpenAPI type
+EXAMPLE_TYPES: "dict[str, tuple[type, ...]]" = {
+    "string": (str,),
+    "integer": (int,),
+    "number": (int, float),
+    "boolean": (bool,),
+}
+
+
+def check_example_type(name: str, arg_type: str, example: "JSONObject") -> None:
+    expected = EXAMPLE_TYPES.get(arg_type)
+    if expected is None:
+        return
+    # bool is a subclass of int, but True isn't a useful example for a number
+    if not isinstance(example, expected) or (
+        isinstance(example, bool) and arg_type != "boolean"
+    ):
+        raise ValueError(
+            f"example {example!r} for input {name} doesn't match its type {arg_type}"
+        )
 
 
-def extract_info(code: str) -> "JSONDict":
-    """Parse the schemas from a file with a predict function"""
+def integer_bound(name: str, key: str, bound: "JSONObject") -> int:
+    """
+    Return a bound of an integer input as an integer, like 10 for le=STEPS / 3.0, because
+    strict validators reject float bounds on integer schemas
+    """
+    if isinstance(bound, float) and bound.is_integer():
+        return int(bound)
+    if isinstance(bound, int) and not isinstance(bound, bool):
+        return bound
+    raise ValueError(
+        f"{key} {bound!r} for input {name} must be a whole number, because the input is an integer"
+    )
+
+
+def extract_info(
+    code: str, base_dir: "str | Path" = ".", class_name: str = "Predictor"
+) -> "JSONDict":
+    """
+    Parse the schemas from a file with a predict function. class_name is the class or
+    function in the predictor ref, like Predictor in predict.py:Predictor.
+    """
     tree = ast.parse(code)
     properties: JSONDict = {}
     inputs: JSONDict = {"title": "Input", "type": "object", "properties": properties}
     required: list[str] = []
     schemas: JSONDict = {}
-    for arg, default in parse_args(tree):
+    scope = collect_module_scope(tree, base_dir)
+    comments = parse_parameter_comments(code, "predict", class_name)
+    for arg, default in parse_args(tree, class_name):
         if arg.arg == "self":
             continue
+        annotation, default = unwrap_annotated(arg.annotation, default)
+        annotation, literal_choices = unwrap_literal(arg.arg, annotation)
         if isinstance(default, ast.Call) and get_call_name(default) == "Input":
             kws = {}
             for kw in default.keywords:
                 if kw.arg is None:
                     msg = "unknown argument for Input"
                     raise ValueError(msg)
-                kws[kw.arg] = to_serializable(get_value(kw.value))
-        elif isinstance(default, (ast.Constant, ast.List, ast.Tuple, ast.Str, ast.Num)):
-            kws = {"default": to_serializable(get_value(default))}  # could be None
+                try:
+                    value = to_serializable(get_value(kw.value, scope))
+                except ValueError as e:
+                    raise ValueError(
+                        f"Could not resolve {kw.arg}={ast.unparse(kw.value)} for input {arg.arg}. "
+                        "It must be a literal, or a module-level constant"
+                    ) from e
+                # choices can be a dict of values to labels
+                if kw.arg == "choices" and isinstance(value, dict):
+                    kws["choices"] = list(value.keys())
+                    kws["choice_labels"] = list(value.values())
+                else:
+                    kws[kw.arg] = value
+        elif isinstance(
+            default,
+            (
+                ast.Constant,
+                ast.List,
+                ast.Tuple,
+                ast.Str,
+                ast.Num,
+                ast.Name,
+                ast.Attribute,
+                ast.BinOp,
+            ),
+        ):
+            kws = {"default": to_serializable(get_value(default, scope))}  # could be None
         elif default == ...:  # no default
             kws = {}
         else:
             raise ValueError("Unexpected default value", default)
+        if literal_choices is not None:
+            kws.setdefault("choices", literal_choices)
         input: JSONDict = {"x-order": len(properties)}
         # need to handle other types?
-        arg_type = OPENAPI_TYPES.get(get_annotation(arg.annotation), "string")
-        if get_annotation(arg.annotation) in ("Path", "File"):
-            input["format"] = "uri"
+        arg_type = OPENAPI_TYPES.get(get_annotation(annotation), "string")
+        input.update(get_format(get_annotation(annotation)))
+        if "example" in kws:
+            check_example_type(arg.arg, arg_type, kws["example"])
+        for example in kws.get("examples", []):
+            check_example_type(arg.arg, arg_type, example)
         for attr in KEPT_ATTRS:
             if attr in kws:
                 input[attr] = kws[attr]
+        for attr, key in FIELD_CONSTRAINTS.items():
+            if attr in kws:
+                input[key] = kws[attr]
+        if arg_type == "integer":
+            for key in ("minimum", "maximum"):
+                if key in input:
+                    input[key] = integer_bound(arg.arg, key, input[key])
+        if "description" not in input and arg.arg in comments:
+            input["description"] = comments[arg.arg]
+        for attr, key in EXTENSION_ATTRS.items():
+            if attr in kws:
+                input[key] = kws[attr]
         if "default" not in input:
             required.append(arg.arg)
+        if "choices" in kws and is_choices_file_ref(kws["choices"]):
+            kws["choices"] = resolve_choices(typing.cast(str, kws["choices"]), base_dir)
         if "choices" in kws and isinstance(kws["choices"], list):
             input["allOf"] = [{"$ref": f"#/components/schemas/{arg.arg}"}]
             # could use type(kws["choices"][0]).__name__
@@ -545,14 +1114,16 @@ def extract_info(code: str) -> "JSONDict":
                 "type": arg_type,
                 "description": "An enumeration.",
             }
+            if "choice_labels" in kws:
+                schemas[arg.arg]["x-enum-labels"] = kws["choice_labels"]
         else:
-            input["title"] = arg.arg.replace("_", " ").title()
+            input.setdefault("title", arg.arg.replace("_", " ").title())
             input["type"] = arg_type
         properties[arg.arg] = input
     if required:
         inputs["required"] = list(required)
     # List[Path], list[Path], str, Iterator[str], MyOutput, Output
-    return_schema, output = parse_return_annotation(tree, "predict")
+    return_schema, output = parse_return_annotation(tree, "predict", class_name)
     schema: JSONDict = json.loads(BASE_SCHEMA)
     components: JSONDict = {
         "Input": inputs,
@@ -563,17 +1134,24 @@ def extract_info(code: str) -> "JSONDict":
     # trust me, typechecker, I know BASE_SCHEMA
     x: JSONDict = schema["components"]["schemas"]  # type: ignore
     x.update(components)
+    description = ast.get_docstring(find_method(tree, "predict", class_name))
+    if description:
+        schema["paths"]["/predictions"]["post"]["description"] = description  # type: ignore
     return schema
 
 
-def extract_file(fname: "str | Path") -> "JSONObject":
-    return extract_info(open(fname, encoding="utf-8").read())
+def extract_file(fname: "str | Path", class_name: str = "Predictor") -> "JSONObject":
+    return extract_info(
+        open(fname, encoding="utf-8").read(), Path(fname).parent, class_name
+    )
 
 
 if __name__ == "__main__":
     if len(sys.argv) > 1:
-        p = Path(sys.argv[1])
+        # a predictor ref, like predict.py:Predictor
+        fname, _, class_name = sys.argv[1].partition(":")
+        p = Path(fname)
         if p.exists():
-            print(json.dumps(extract_file(p)))
+            print(json.dumps(extract_file(p, class_name or "Predictor")))
     else:
         print(json.dumps(extract_info(sys.stdin.read())))
@@ -1,5 +1,5 @@
 import io
-from datetime import datetime
+from datetime import date
 from enum import Enum
 from types import GeneratorType
 from typing import Any, Callable
@@ -25,7 +25,8 @@ def make_encodeable(obj: Any) -> Any:
         return [make_encodeable(value) for value in obj]
     if isinstance(obj, Enum):
         return obj.value
-    if isinstance(obj, datetime):
+    # datetime is a subclass of date
+    if isinstance(obj, date):
         return obj.isoformat()
     try:
         import numpy as np  # type: ignore
@@ -1,3 +1,5 @@
+import copy
+import datetime
 import enum
 import importlib.util
 import inspect
@@ -5,6 +7,7 @@ import io
 import os.path
 import sys
 import types
+import typing
 import uuid
 from abc import ABC, abstractmethod
 from collections.abc import Iterator
@@ -36,8 +39,13 @@ from pydantic import BaseModel, Field, create_model
 from pydantic.fields import FieldInfo
 
 # Added in Python 3.9. Can be from typing if we drop support for <3.9
-from typing_extensions import Annotated
+from typing_extensions import Annotated, Literal
 
+from .command.ast_openapi_schema import (
+    is_choices_file_ref,
+    parse_parameter_comments,
+    resolve_choices,
+)
 from .errors import ConfigDoesNotExist, PredictorNotSet
 from .types import (
     CogConfig,
@@ -54,11 +62,16 @@ from .types import Secret as CogSecret
 
 log = structlog.get_logger("cog.server.predictor")
 
+# typing_extensions has its own Literal on Python < 3.10.1, so predictors can use either
+LITERAL_TYPES = {Literal, getattr(typing, "Literal", Literal)}
+
 ALLOWED_INPUT_TYPES: List[Type[Any]] = [
     str,
     int,
     float,
     bool,
+    datetime.datetime,
+    datetime.date,
     CogFile,
     CogPath,
     CogSecret,
@@ -213,6 +226,10 @@ def load_slim_predictor_from_file(
         source_code, class_name, method_name
     )
     module = code_xforms.load_module_from_string(uuid.uuid4().hex, stripped_source)
+    if module:
+        # The stripped source doesn't have comments, so keep track of where the
+        # original is for parameter descriptions
+        module.__file__ = module_path
     return module
 
 
@@ -291,8 +308,73 @@ def validate_input_type(type: Type[Any], name: str) -> None:
             )
 
 
+# The Python types an example value can have for each primitive input type
+EXAMPLE_TYPES: Dict[Any, Any] = {
+    str: str,
+    int: int,
+    float: (int, float),
+    bool: bool,
+    datetime.datetime: str,
+    datetime.date: str,
+    CogPath: str,
+    CogFile: str,
+    CogSecret: str,
+}
+
+
+def validate_input_example(type: Type[Any], name: str, example: Any) -> None:
+    expected = EXAMPLE_TYPES.get(type)
+    if expected is None:
+        return
+    # bool is a subclass of int, but True isn't a useful example for a number
+    if not isinstance(example, expected) or (
+        isinstance(example, bool) and type is not bool
+    ):
+        raise TypeError(
+            f"The example {example!r} for parameter `{name}` doesn't match its type {human_readable_type_name(type)}."
+        )
+
+
+def _enum_labels_schema_modifier(labels: List[Any]) -> Any:
+    def modify_schema(field_schema: Dict[str, Any]) -> None:
+        field_schema["x-enum-labels"] = labels
+
+    return staticmethod(modify_schema)
+
+
+def _literal_type(values: List[Any], name: str) -> Type[Any]:
+    for t in (str, int):
+        if all(type(v) is t for v in values):
+            return t
+    raise TypeError(
+        f"The Literal values for parameter `{name}` must all be strings or all be integers."
+    )
+
+
+def _integer_bounds(field: FieldInfo, name: str) -> None:
+    """Makes the ge and le of an int input integers, so the schema has integer bounds."""
+    for attr in ("ge", "le"):
+        bound = getattr(field, attr)
+        if isinstance(bound, float):
+            if not bound.is_integer():
+                raise TypeError(
+                    f"The {attr} of parameter `{name}` must be a whole number, because `{name}` is an int."
+                )
+            setattr(field, attr, int(bound))
+
+
+def _annotated_input(annotation: Any) -> Optional[FieldInfo]:
+    for meta in getattr(annotation, "__metadata__", ()):
+        if isinstance(meta, FieldInfo):
+            return meta
+    return None
+
+
 def get_input_create_model_kwargs(
-    signature: inspect.Signature, input_types: Dict[str, Any]
+    signature: inspect.Signature,
+    input_types: Dict[str, Any],
+    base_dir: str = ".",
+    comments: Optional[Dict[str, str]] = None,
 ) -> Dict[str, Any]:
     create_model_kwargs = {}
 
@@ -304,10 +386,24 @@ def get_input_create_model_kwargs(
 
         InputType = input_types[name]
 
+        # Literal["a", "b"] is shorthand for str with choices=["a", "b"]
+        literal_choices = None
+        if get_origin(InputType) in LITERAL_TYPES:
+            literal_choices = list(get_args(InputType))
+            InputType = _literal_type(literal_choices, name)
+
         validate_input_type(InputType, name)
 
+        # Annotated[T, Input(...)] puts the Input in the annotation instead of the default
+        annotated = _annotated_input(parameter.annotation)
+        if annotated is not None:
+            # The annotation can be a type alias shared by other parameters, so don't
+            # change it when setting the default and the order below
+            default = copy.deepcopy(annotated)
+            if parameter.default is not inspect.Signature.empty:
+                default.default = parameter.default
         # if no default is specified, create an empty, required input
-        if parameter.default is inspect.Signature.empty:
+        elif parameter.default is inspect.Signature.empty:
             default = Input()
         else:
             default = parameter.default
@@ -315,16 +411,39 @@ def get_input_create_model_kwargs(
             if not isinstance(default, FieldInfo):
                 default = Input(default=default)
 
+        if InputType == int:  # noqa: E721
+            _integer_bounds(default, name)
+
+        # A trailing comment on the parameter's line is used if there's no description
+        if default.description is None and comments and name in comments:
+            default.description = comments[name]
+
         # Fields aren't ordered, so use this pattern to ensure defined order
         # https://github.com/go-openapi/spec/pull/116
         default.extra["x-order"] = order
         order += 1
 
+        if literal_choices is not None and not default.extra.get("choices"):
+            default.extra["choices"] = literal_choices
+
+        if "example" in default.extra:
+            validate_input_example(InputType, name, default.extra["example"])
+        for example in default.extra.get("examples", []):
+            validate_input_example(InputType, name, example)
+
         # Choices!
         if default.extra.get("choices"):
             choices = default.extra["choices"]
             # It will be passed automatically as 'enum' in the schema, so remove it as an extra field.
             del default.extra["choices"]
+            # Choices can be loaded from a file next to the predictor, e.g. "@choices.json:models"
+            if is_choices_file_ref(choices):
+                choices = resolve_choices(choices, base_dir)
+            # A dict of choices maps values to human-readable labels
+            labels = None
+            if isinstance(choices, dict):
+                labels = list(choices.values())
+                choices = list(choices.keys())
             if InputType == str:  # noqa: E721
 
                 class StringEnum(str, enum.Enum):
@@ -339,6 +458,8 @@ def get_input_create_model_kwargs(
                 raise TypeError(
                     f"The input {name} uses the option choices. Choices can only be used with str or int types."
                 )
+            if labels is not None:
+                InputType.__modify_schema__ = _enum_labels_schema_modifier(labels)  # type: ignore
 
         create_model_kwargs[name] = (InputType, default)
 
@@ -351,6 +472,36 @@ def get_predict(predictor: Any) -> Callable[..., Any]:
     return predictor
 
 
+def get_predict_description(predictor: Any) -> Optional[str]:
+    """
+    Returns the docstring of the predictor's predict() method, which is used as the
+    description of the prediction endpoint.
+    """
+    # Not inspect.getdoc(), which would inherit BasePredictor.predict's docstring
+    doc = get_predict(predictor).__doc__
+    return inspect.cleandoc(doc) if doc else None
+
+
+def _parameter_comments(fn: Callable[..., Any]) -> Dict[str, str]:
+    source_file = getattr(fn, "__func__", fn).__globals__.get("__file__")
+    if not source_file:
+        return {}
+    # For function predictors, the function is what the predictor ref names
+    class_name = type(fn.__self__).__name__ if inspect.ismethod(fn) else fn.__name__
+    try:
+        with open(source_file, encoding="utf-8") as f:
+            return parse_parameter_comments(f.read(), fn.__name__, class_name)
+    except (OSError, SyntaxError, ValueError, StopIteration):
+        return {}
+
+
+def _source_dir(fn: Callable[..., Any]) -> str:
+    try:
+        return os.path.dirname(inspect.getfile(fn))
+    except TypeError:
+        return "."
+
+
 def get_input_type(predictor: BasePredictor) -> Type[BaseInput]:
     """
     Creates a Pydantic Input model from the arguments of a Predictor's predict() method.
@@ -378,7 +529,12 @@ def get_input_type(predictor: BasePredictor) -> Type[BaseInput]:
         __base__=BaseInput,
         __module__=__name__,
         __validators__=None,
-        **get_input_create_model_kwargs(signature, input_types),
+        **get_input_create_model_kwargs(
+            signature,
+            input_types,
+            _source_dir(predict),
+            _parameter_comments(predict),
+        ),
     )  # type: ignore
 
 
@@ -480,7 +636,12 @@ def get_training_input_type(predictor: BasePredictor) -> Type[BaseInput]:
         __base__=BaseInput,
         __module__=__name__,
         __validators__=None,
-        **get_input_create_model_kwargs(signature, input_types),
+        **get_input_create_model_kwargs(
+            signature,
+            input_types,
+            _source_dir(train),
+            _parameter_comments(train),
+        ),
     )  # type: ignore
 
 
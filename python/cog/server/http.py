@@ -16,6 +16,8 @@ from typing import (
     Any,
     Awaitable,
     Callable,
+    Dict,
+    List,
     Optional,
     TypeVar,
 )
@@ -26,7 +28,7 @@ if TYPE_CHECKING:
 import attrs
 import structlog
 import uvicorn
-from fastapi import Body, FastAPI, Header, HTTPException, Path, Response
+from fastapi import Body, FastAPI, Header, Path, Response
 from fastapi.encoders import jsonable_encoder
 from fastapi.exceptions import RequestValidationError
 from fastapi.responses import JSONResponse
@@ -41,6 +43,7 @@ from ..logging import setup_logging
 from ..predictor import (
     get_input_type,
     get_output_type,
+    get_predict_description,
     get_predictor_ref,
     get_training_input_type,
     get_training_output_type,
@@ -130,11 +133,16 @@ def create_app(
         predictor = load_slim_predictor_from_ref(predictor_ref, "predict")
         InputType = get_input_type(predictor)
         OutputType = get_output_type(predictor)
+        predict_description = get_predict_description(predictor)
     except Exception:
         msg = "Error while loading predictor:\n\n" + traceback.format_exc()
         add_setup_failed_routes(app, started_at, msg)
         return app
 
+    # When set, outputs that don't match the declared output type are returned
+    # as-is rather than failing the request. This is intended for development.
+    lenient_output = os.environ.get("COG_LENIENT_OUTPUT", "") in ("1", "true")
+
     runner = PredictionRunner(
         predictor_ref=predictor_ref,
         shutdown_event=shutdown_event,
@@ -270,6 +278,8 @@ def create_app(
         "/predictions",
         response_model=PredictionResponse,
         response_model_exclude_unset=True,
+        # falls back to the docstring of this function if predict() doesn't have one
+        description=predict_description,
     )
     async def predict(
         request: PredictionRequest = Body(default=None),
@@ -366,13 +376,23 @@ def create_app(
         if respond_async:
             return JSONResponse(jsonable_encoder(initial_response), status_code=202)
 
+        result = async_result.get()
         try:
-            response = PredictionResponse(**async_result.get().dict())
+            response_object = PredictionResponse(**result.dict()).dict()
         except ValidationError as e:
             _log_invalid_output(e)
-            raise HTTPException(status_code=500, detail=str(e)) from e
+            if not lenient_output:
+                return JSONResponse(
+                    {
+                        "detail": "The return value of predict() does not match its output type",
+                        "errors": _invalid_output_errors(e),
+                    },
+                    status_code=500,
+                )
+            # In lenient mode, return the output as-is so the model author can
+            # see what predict() actually returned.
+            response_object = result.dict()
 
-        response_object = response.dict()
         response_object["output"] = upload_files(
             response_object["output"],
             upload_file=lambda fh: upload_file(fh, request.output_file_prefix),  # type: ignore
@@ -435,6 +455,24 @@ def _log_invalid_output(error: Any) -> None:
     )
 
 
+def _invalid_output_errors(error: ValidationError) -> List[Dict[str, Any]]:
+    errors = []
+    for e in error.errors():
+        loc = list(e["loc"])
+        # Errors are reported relative to the response, so drop the leading
+        # "output" to make them relative to the return value of predict().
+        if loc and loc[0] == "output":
+            loc = loc[1:]
+        errors.append(
+            {
+                "field": ".".join(str(part) for part in loc),
+                "message": e["msg"],
+                "type": e["type"],
+            }
+        )
+    return errors
+
+
 class Server(uvicorn.Server):
     def start(self) -> None:
         self._thread = threading.Thread(target=self.run)
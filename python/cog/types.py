@@ -19,6 +19,9 @@ FILENAME_ILLEGAL_CHARS = set("\u0000/")
 # tempfile.NamedTemporaryFile, etc.
 FILENAME_MAX_LENGTH = 200
 
+CONTENT_TYPES_KEY = "x-cog-content-types"
+EXTENSIONS_KEY = "x-cog-extensions"
+
 
 class CogConfig(TypedDict):
     build: "CogBuildConfig"
@@ -39,17 +42,35 @@ class CogBuildConfig(TypedDict, total=False):
 
 def Input(
     default: Any = ...,
+    title: str = None,
     description: str = None,
     ge: float = None,
     le: float = None,
     min_length: int = None,
     max_length: int = None,
     regex: str = None,
-    choices: List[Union[str, int]] = None,
+    choices: Union[List[Union[str, int]], Dict[Union[str, int], str], str] = None,
+    content_types: List[str] = None,
+    extensions: List[str] = None,
+    example: Any = None,
+    examples: List[Any] = None,
 ) -> Any:
     """Input is similar to pydantic.Field, but doesn't require a default value to be the first argument."""
+    # File constraints are vendor extensions in the schema, and are checked by Path.validate
+    file_constraints: Dict[str, Any] = {}
+    if content_types is not None:
+        file_constraints[CONTENT_TYPES_KEY] = content_types
+    if extensions is not None:
+        file_constraints[EXTENSIONS_KEY] = extensions
+    # Examples are only emitted in the schema when they're set
+    examples_extra: Dict[str, Any] = {}
+    if example is not None:
+        examples_extra["example"] = example
+    if examples is not None:
+        examples_extra["examples"] = examples
     return Field(
         default,
+        title=title,
         description=description,
         ge=ge,
         le=le,
@@ -57,6 +78,8 @@ def Input(
         max_length=max_length,
         regex=regex,
         choices=choices,
+        **file_constraints,
+        **examples_extra,
     )
 
 
@@ -113,13 +136,17 @@ class Path(pathlib.PosixPath):
         yield cls.validate
 
     @classmethod
-    def validate(cls, value: Any) -> pathlib.Path:
+    def validate(cls, value: Any, field: Any = None) -> pathlib.Path:
         if isinstance(value, pathlib.Path):
             return value
 
+        filename = get_filename(value)
+        if field is not None:
+            check_file_constraints(value, filename, field.field_info.extra)
+
         return URLPath(
             source=value,
-            filename=get_filename(value),
+            filename=filename,
             fileobj=File.validate(value),
         )
 
@@ -259,6 +286,45 @@ def get_filename(url: str) -> str:
     return basename
 
 
+def check_file_constraints(url: str, filename: str, constraints: Dict[str, Any]) -> None:
+    """
+    Raise a ValueError if the file at url doesn't match the content types or
+    extensions allowed by Input(content_types=..., extensions=...).
+    """
+    content_types = constraints.get(CONTENT_TYPES_KEY)
+    if content_types:
+        content_type = get_content_type(url, filename)
+        if not any(_content_type_matches(content_type, ct) for ct in content_types):
+            raise ValueError(
+                f"File has content type {content_type or 'unknown'}, but must be one of: {', '.join(content_types)}"
+            )
+
+    extensions = constraints.get(EXTENSIONS_KEY)
+    if extensions:
+        allowed = [("." + ext.lstrip(".")).lower() for ext in extensions]
+        if not filename.lower().endswith(tuple(allowed)):
+            raise ValueError(
+                f"File {filename} must have one of these extensions: {', '.join(allowed)}"
+            )
+
+
+def get_content_type(url: str, filename: str) -> Optional[str]:
+    if url.startswith("data:"):
+        # data:[<mediatype>][;base64],<data>
+        media_type = url[len("data:") :].split(",", 1)[0].split(";", 1)[0]
+        return media_type or "text/plain"
+    content_type, _ = mimetypes.guess_type(filename)
+    return content_type
+
+
+def _content_type_matches(content_type: Optional[str], pattern: str) -> bool:
+    if content_type is None:
+        return False
+    if pattern.endswith("/*"):
+        return content_type.startswith(pattern[:-1])
+    return content_type == pattern
+
+
 Item = TypeVar("Item")
 
 
@@ -101,5 +101,5 @@ def client(request):
 @pytest.fixture
 def static_schema(client) -> dict:
     ref = _fixture_path(client.ref)
-    module_path = ref.split(":", 1)[0]
-    return ast_openapi_schema.extract_file(module_path)
+    module_path, class_name = ref.split(":", 1)
+    return ast_openapi_schema.extract_file(module_path, class_name)
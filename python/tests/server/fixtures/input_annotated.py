@@ -0,0 +1,12 @@
+from cog import BasePredictor, Input
+from typing_extensions import Annotated
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        prompt: Annotated[str, Input(description="What to generate")],
+        scheduler: Annotated[str, Input(default="ddim", choices=["ddim", "euler"])],
+        steps: Annotated[int, Input(description="Number of steps", ge=1, le=100)] = 50,
+    ) -> str:
+        return f"{prompt} {scheduler} {steps}"
@@ -0,0 +1,11 @@
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        prompt: str = Input(description="What to generate"),
+        scheduler: str = Input(default="ddim", choices=["ddim", "euler"]),
+        steps: int = Input(default=50, description="Number of steps", ge=1, le=100),
+    ) -> str:
+        return f"{prompt} {scheduler} {steps}"
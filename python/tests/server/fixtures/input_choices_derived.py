@@ -0,0 +1,15 @@
+from cog import BasePredictor, Input
+
+ASPECT_RATIOS = {"1:1": (1024, 1024), "16:9": (1344, 768), "9:16": (768, 1344)}
+RATIO_CHOICES = list(ASPECT_RATIOS.keys())
+SCHEDULERS = {"ddim": "DDIM", "euler": "Euler"}
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        aspect_ratio: str = Input(default="1:1", choices=RATIO_CHOICES),
+        scheduler: str = Input(default="ddim", choices=sorted(SCHEDULERS)),
+    ) -> str:
+        width, height = ASPECT_RATIOS[aspect_ratio]
+        return f"{width}x{height} {scheduler}"
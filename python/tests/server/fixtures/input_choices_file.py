@@ -0,0 +1,8 @@
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self, model: str = Input(choices="@input_choices_file.json:models")
+    ) -> str:
+        return model
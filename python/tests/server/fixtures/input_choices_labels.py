@@ -0,0 +1,12 @@
+from cog import BasePredictor, Input
+
+MODES = {"fast": "Fast Mode", "quality": "High Quality"}
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        mode: str = Input(choices=MODES),
+        size: int = Input(choices={512: "Small", 1024: "Large"}),
+    ) -> str:
+        return f"{mode} {size}"
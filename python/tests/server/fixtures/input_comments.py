@@ -0,0 +1,14 @@
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        prompt: str,  # What to generate
+        steps: int = Input(
+            default=50, ge=1
+        ),  # Number of denoising steps
+        seed: int = Input(default=0, description="Random seed"),  # Ignored
+        width: int = 512, height: int = 512,  # Applies to both, so it's ignored
+    ) -> str:
+        return f"{prompt} {steps} {seed} {width}x{height}"
@@ -0,0 +1,14 @@
+from cog import BasePredictor, Input
+
+MIN_GUIDANCE = 1.0
+MAX_GUIDANCE = MIN_GUIDANCE * 20
+SCHEDULERS = ["ddim", "euler"]
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        guidance: float = Input(default=7.5, ge=MIN_GUIDANCE, le=MAX_GUIDANCE),
+        scheduler: str = Input(default="ddim", choices=SCHEDULERS),
+    ) -> str:
+        return f"{guidance} {scheduler}"
@@ -0,0 +1,12 @@
+import datetime
+
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        start: datetime.datetime = Input(description="When to start"),
+        day: datetime.date,
+    ) -> datetime.datetime:
+        return datetime.datetime.combine(day, start.time())
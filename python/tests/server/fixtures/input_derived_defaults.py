@@ -0,0 +1,14 @@
+from cog import BasePredictor, Input
+
+STEPS = 30
+GUIDANCE = STEPS / 3.0
+MAX_STEPS: int = STEPS * 2 + 10
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        steps: int = Input(default=STEPS, le=MAX_STEPS),
+        guidance: float = GUIDANCE,
+    ) -> str:
+        return f"{steps} {guidance}"
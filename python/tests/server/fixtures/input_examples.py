@@ -0,0 +1,11 @@
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        prompt: str = Input(example="an astronaut riding a horse"),
+        steps: int = Input(default=50, examples=[25, 50]),
+        guidance: float = Input(default=7.5, example=7),
+    ) -> str:
+        return f"{prompt} {steps} {guidance}"
@@ -0,0 +1,12 @@
+from typing import Literal
+
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        mode: Literal["fast", "slow"] = "fast",
+        steps: Literal[10, 20, 50] = Input(default=20, description="Number of steps"),
+    ) -> str:
+        return f"{mode} {steps}"
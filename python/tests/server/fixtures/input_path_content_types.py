@@ -0,0 +1,13 @@
+from cog import BasePredictor, Input, Path
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        image: Path = Input(
+            description="An image",
+            content_types=["image/png", "image/jpeg"],
+            extensions=["png", "jpg"],
+        ),
+    ) -> str:
+        return image.name
@@ -0,0 +1,16 @@
+from cog import BasePredictor, Input
+
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        guidance_scale: float = Input(
+            default=7.5,
+            title="Guidance Scale",
+            description="How closely to follow the prompt",
+        ),
+        sampler: str = Input(
+            default="ddim", title="Sampling Method", choices=["ddim", "euler"]
+        ),
+    ) -> str:
+        return f"{guidance_scale} {sampler}"
@@ -0,0 +1,13 @@
+from cog import BasePredictor
+from pydantic import BaseModel, Field
+
+
+class Prediction(BaseModel):
+    label: str
+    score: float = Field(default=0.0, ge=0, le=1)
+    caption: str = Field("", max_length=280)
+
+
+class Predictor(BasePredictor):
+    def predict(self) -> Prediction:
+        return Prediction(label="cat", score=0.9)
@@ -0,0 +1,8 @@
+from typing import Tuple
+
+from cog import BasePredictor
+
+
+class Predictor(BasePredictor):
+    def predict(self, text: str) -> Tuple[str, float, Tuple[int, bool]]:
+        return text, 0.5, (len(text), True)
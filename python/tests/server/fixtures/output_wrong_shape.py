@@ -0,0 +1,12 @@
+from cog import BasePredictor
+from pydantic import BaseModel
+
+
+class Output(BaseModel):
+    number: int
+    text: str
+
+
+class Predictor(BasePredictor):
+    def predict(self) -> Output:
+        return {"number": 42}
@@ -0,0 +1,11 @@
+from cog import BasePredictor
+
+
+class Predictor(BasePredictor):
+    def predict(self, text: str) -> str:
+        """
+        Reverse some text.
+
+        Works with any unicode string.
+        """
+        return text[::-1]
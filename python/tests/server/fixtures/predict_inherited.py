@@ -0,0 +1,10 @@
+from cog import BasePredictor
+
+
+class BaseGreeter(BasePredictor):
+    def predict(self, text: str) -> str:
+        return "hello " + text
+
+
+class Predictor(BaseGreeter):
+    pass
@@ -0,0 +1,15 @@
+from cog import BasePredictor
+
+
+class LoggingMixin:
+    def predict(self, message: int) -> int:
+        return message
+
+    def log(self, message: str) -> None:
+        print(message)
+
+
+class Predictor(LoggingMixin, BasePredictor):
+    def predict(self, text: str) -> str:
+        self.log(text)
+        return "hello " + text
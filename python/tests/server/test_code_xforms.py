@@ -76,3 +76,16 @@ class Predictor(BasePredictor):
 """
     assert expected_source.strip() == new_source.strip()
     assert code_xforms.load_module_from_string(uuid.uuid4().hex, new_source)
+
+
+@pytest.mark.skipif(sys.version_info < (3, 9), reason="requires python3.9 or higher")
+def test_predict_docstring_is_kept():
+    with open(f"{g_module_dir}/fixtures/predict_docstring.py", encoding="utf-8") as file:
+        source_code = file.read()
+
+    new_source = code_xforms.strip_model_source_code(
+        source_code, "Predictor", "predict"
+    )
+    module = code_xforms.load_module_from_string(uuid.uuid4().hex, new_source)
+    assert module.Predictor.predict.__doc__.strip().startswith("Reverse some text.")
+    assert module.Predictor().predict("abc") is None
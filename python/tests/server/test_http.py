@@ -5,9 +5,11 @@ import unittest.mock as mock
 
 import responses
 from PIL import Image
+from cog.command import ast_openapi_schema
 from responses import matchers
 
 from .conftest import (
+    _fixture_path,
     make_client,
     uses_predictor,
     uses_predictor_with_client_options,
@@ -37,6 +39,48 @@ def test_predict_works_with_functions(client, match):
     assert resp.json() == match({"status": "succeeded", "output": "hello baz"})
 
 
+@uses_predictor("predict_mixin_first")
+def test_predict_with_mixin_before_base_predictor(client, match, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.json() == static_schema
+    assert static_schema["components"]["schemas"]["Input"]["properties"]["text"][
+        "type"
+    ] == "string"
+
+    resp = client.post("/predictions", json={"input": {"text": "baz"}})
+    assert resp.status_code == 200
+    assert resp.json() == match({"status": "succeeded", "output": "hello baz"})
+
+
+@uses_predictor("predict_inherited")
+def test_predict_inherited_from_base_class(client, match, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.json() == static_schema
+
+    resp = client.post("/predictions", json={"input": {"text": "baz"}})
+    assert resp.status_code == 200
+    assert resp.json() == match({"status": "succeeded", "output": "hello baz"})
+
+
+def _static_schema_for(name):
+    module_path, class_name = _fixture_path(name).split(":", 1)
+    return ast_openapi_schema.extract_file(module_path, class_name)
+
+
+@uses_predictor("predict_async_str")
+def test_async_predict_schema_matches_sync(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.json() == static_schema
+    assert static_schema == _static_schema_for("input_string")
+
+
+@uses_predictor("predict_async_output")
+def test_async_predict_output_object_schema_matches_sync(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.json() == static_schema
+    assert static_schema == _static_schema_for("openapi_output_type")
+
+
 @uses_predictor("openapi_complex_input")
 def test_openapi_specification(client, static_schema):
     resp = client.get("/openapi.json")
@@ -630,3 +674,82 @@ def test_weights_are_read_from_environment_variables(client, match):
     resp = client.post("/predictions")
     assert resp.status_code == 200
     assert resp.json() == match({"status": "succeeded", "output": "hello"})
+
+
+@uses_predictor("openapi_output_field_defaults")
+def test_openapi_specification_with_output_field_defaults(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    schema = resp.json()
+    assert schema == static_schema
+    assert schema["components"]["schemas"]["Prediction"] == {
+        "title": "Prediction",
+        "type": "object",
+        "properties": {
+            "label": {"title": "Label", "type": "string"},
+            "score": {
+                "title": "Score",
+                "type": "number",
+                "default": 0.0,
+                "minimum": 0,
+                "maximum": 1,
+            },
+            "caption": {
+                "title": "Caption",
+                "type": "string",
+                "default": "",
+                "maxLength": 280,
+            },
+        },
+        "required": ["label"],
+    }
+
+
+@uses_predictor("predict_docstring")
+def test_openapi_specification_with_predict_docstring(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    schema = resp.json()
+    assert schema == static_schema
+    assert (
+        schema["paths"]["/predictions"]["post"]["description"]
+        == "Reverse some text.\n\nWorks with any unicode string."
+    )
+
+
+@uses_predictor("input_string")
+def test_openapi_specification_without_predict_docstring(client, static_schema):
+    schema = client.get("/openapi.json").json()
+    assert schema == static_schema
+    assert (
+        schema["paths"]["/predictions"]["post"]["description"]
+        == "Run a single prediction on the model"
+    )
+
+
+@uses_predictor("output_tuple")
+def test_openapi_specification_with_tuple_output(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    schema = resp.json()
+    assert schema == static_schema
+    assert schema["components"]["schemas"]["Output"] == {
+        "title": "Output",
+        "type": "array",
+        "minItems": 3,
+        "maxItems": 3,
+        "items": [
+            {"type": "string"},
+            {"type": "number"},
+            {
+                "type": "array",
+                "minItems": 2,
+                "maxItems": 2,
+                "items": [{"type": "integer"}, {"type": "boolean"}],
+            },
+        ],
+    }
+
+    resp = client.post("/predictions", json={"input": {"text": "hello"}})
+    assert resp.status_code == 200
+    assert resp.json()["output"] == ["hello", 0.5, [5, True]]
@@ -2,8 +2,10 @@ import base64
 import os
 import threading
 
+import pytest
 import responses
 from cog import schema
+from cog.command import ast_openapi_schema
 from cog.server.http import Health, create_app
 
 from tests.server.conftest import _fixture_path
@@ -148,6 +150,41 @@ def test_path_input_with_http_url(client, match):
     assert resp.json() == match({"output": "txt hello", "status": "succeeded"})
 
 
+@uses_predictor("input_path_content_types")
+def test_path_input_content_types(client, static_schema):
+    resp = client.get("/openapi.json")
+    image = resp.json()["components"]["schemas"]["Input"]["properties"]["image"]
+    assert image["x-cog-content-types"] == ["image/png", "image/jpeg"]
+    assert image["x-cog-extensions"] == ["png", "jpg"]
+    assert static_schema["components"]["schemas"]["Input"]["properties"]["image"] == image
+
+    resp = client.post(
+        "/predictions",
+        json={
+            "input": {
+                "image": "data:image/png;base64,"
+                + base64.b64encode(b"not really a png").decode("utf-8")
+            }
+        },
+    )
+    assert resp.status_code == 200
+
+
+@uses_predictor("input_path_content_types")
+def test_path_input_disallowed_content_type(client):
+    resp = client.post(
+        "/predictions",
+        json={
+            "input": {
+                "image": "data:text/plain;base64,"
+                + base64.b64encode(b"bar").decode("utf-8")
+            }
+        },
+    )
+    assert resp.status_code == 422
+    assert "content type text/plain" in resp.text
+
+
 @uses_predictor("input_file")
 def test_file_bad_input(client):
     resp = client.post(
@@ -209,6 +246,58 @@ def test_choices_int(client):
     assert resp.status_code == 422
 
 
+@uses_predictor("input_choices_file")
+def test_choices_from_file(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    schema = resp.json()
+    assert schema["components"]["schemas"]["model"]["enum"] == [
+        "sdxl",
+        "flux-dev",
+        "flux-schnell",
+    ]
+    assert static_schema["components"]["schemas"]["model"]["enum"] == [
+        "sdxl",
+        "flux-dev",
+        "flux-schnell",
+    ]
+
+    resp = client.post("/predictions", json={"input": {"model": "flux-dev"}})
+    assert resp.status_code == 200
+    resp = client.post("/predictions", json={"input": {"model": "sd15"}})
+    assert resp.status_code == 422
+
+
+def test_choices_from_file_missing_key():
+    code = """
+class Predictor:
+    def predict(self, model: str = Input(choices="@input_choices_file.json:nope")) -> str:
+        return model
+"""
+    fixtures_dir = os.path.dirname(_fixture_path("input_choices_file"))
+    with pytest.raises(ast_openapi_schema.ChoicesNotResolvable):
+        ast_openapi_schema.extract_info(code, fixtures_dir)
+
+
+@uses_predictor("input_choices_labels")
+def test_choices_with_labels(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    schemas = resp.json()["components"]["schemas"]
+    assert schemas["mode"]["enum"] == ["fast", "quality"]
+    assert schemas["mode"]["x-enum-labels"] == ["Fast Mode", "High Quality"]
+    assert schemas["size"]["enum"] == [512, 1024]
+    assert schemas["size"]["x-enum-labels"] == ["Small", "Large"]
+    assert static_schema["components"]["schemas"]["mode"] == schemas["mode"]
+    assert static_schema["components"]["schemas"]["size"] == schemas["size"]
+
+    resp = client.post(
+        "/predictions", json={"input": {"mode": "fast", "size": 512}}
+    )
+    assert resp.status_code == 200
+    assert resp.json()["output"] == "fast 512"
+
+
 @uses_predictor("input_union_string_or_list_of_strings")
 def test_union_strings(client):
     resp = client.post("/predictions", json={"input": {"args": "abc"}})
@@ -279,3 +368,411 @@ def test_input_with_unsupported_type():
         "TypeError: Unsupported input type input_unsupported_type"
         in app.state.setup_result.logs
     )
+
+
+@uses_predictor("input_examples")
+def test_input_examples(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    properties = resp.json()["components"]["schemas"]["Input"]["properties"]
+    assert properties["prompt"]["example"] == "an astronaut riding a horse"
+    assert properties["steps"]["examples"] == [25, 50]
+    assert properties["guidance"]["example"] == 7
+
+
+def test_input_example_type_mismatch_in_static_schema():
+    code = """
+class Predictor:
+    def predict(self, steps: int = Input(example="fifty")) -> int:
+        return steps
+"""
+    with pytest.raises(ValueError, match="doesn't match its type integer"):
+        ast_openapi_schema.extract_info(code)
+
+
+@uses_predictor("input_derived_defaults")
+def test_defaults_derived_from_module_constants(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    properties = static_schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["steps"]["default"] == 30
+    assert properties["steps"]["maximum"] == 70
+    assert properties["guidance"]["default"] == 10.0
+
+
+def test_default_from_non_constant_expression_is_unresolvable():
+    code = """
+import os
+
+WORKERS = os.cpu_count() * 2
+
+class Predictor:
+    def predict(self, workers: int = WORKERS) -> int:
+        return workers
+"""
+    with pytest.raises(ValueError):
+        ast_openapi_schema.extract_info(code)
+
+
+@uses_predictor("input_title")
+def test_input_title(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    properties = static_schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["guidance_scale"]["title"] == "Guidance Scale"
+    assert (
+        properties["guidance_scale"]["description"]
+        == "How closely to follow the prompt"
+    )
+    assert properties["sampler"]["title"] == "Sampling Method"
+
+
+@uses_predictor("input_constant_constraints")
+def test_constraints_from_module_constants(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    schemas = static_schema["components"]["schemas"]
+    assert schemas["Input"]["properties"]["guidance"]["minimum"] == 1.0
+    assert schemas["Input"]["properties"]["guidance"]["maximum"] == 20.0
+    assert schemas["scheduler"]["enum"] == ["ddim", "euler"]
+
+
+def test_integer_input_bounds_are_integers():
+    code = """
+STEPS = 30
+
+class Predictor:
+    def predict(self, steps: int = Input(default=10, ge=1, le=STEPS / 3.0)) -> int:
+        return steps
+"""
+    schema = ast_openapi_schema.extract_info(code)
+    steps = schema["components"]["schemas"]["Input"]["properties"]["steps"]
+    assert steps["minimum"] == 1
+    assert steps["maximum"] == 10
+    assert type(steps["maximum"]) is int
+
+
+def test_non_integral_bound_on_integer_input():
+    code = """
+STEPS = 50
+
+class Predictor:
+    def predict(self, steps: int = Input(default=10, le=STEPS / 3.0)) -> int:
+        return steps
+"""
+    with pytest.raises(ValueError, match="maximum 16.66.* for input steps must be a whole number"):
+        ast_openapi_schema.extract_info(code)
+
+
+def test_unresolvable_constraint_names_the_input():
+    code = """
+from limits import MAX_STEPS
+
+class Predictor:
+    def predict(self, steps: int = Input(le=MAX_STEPS)) -> int:
+        return steps
+"""
+    with pytest.raises(ValueError, match="Could not resolve le=MAX_STEPS for input steps"):
+        ast_openapi_schema.extract_info(code)
+
+
+def test_default_imported_from_local_module(tmp_path):
+    (tmp_path / "defaults.py").write_text("BASE_STEPS = 25\n")
+    (tmp_path / "constants.py").write_text(
+        """
+import os
+from defaults import BASE_STEPS
+
+DEFAULT_PROMPT = "an astronaut riding a horse"
+DEFAULT_STEPS = BASE_STEPS * 2
+WORKERS = os.cpu_count()
+"""
+    )
+    code = """
+from cog import BasePredictor, Input
+from constants import DEFAULT_PROMPT, DEFAULT_STEPS as STEPS
+
+class Predictor(BasePredictor):
+    def predict(self, prompt: str = Input(default=DEFAULT_PROMPT), steps: int = STEPS) -> str:
+        return prompt
+"""
+    schema = ast_openapi_schema.extract_info(code, tmp_path)
+    properties = schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["prompt"]["default"] == "an astronaut riding a horse"
+    assert properties["steps"]["default"] == 50
+
+    # Imported values that aren't constants still can't be resolved
+    code = """
+from constants import WORKERS
+
+class Predictor:
+    def predict(self, workers: int = Input(default=WORKERS)) -> int:
+        return workers
+"""
+    with pytest.raises(ValueError, match="Could not resolve default=WORKERS"):
+        ast_openapi_schema.extract_info(code, tmp_path)
+
+
+def test_constants_imported_from_local_packages(tmp_path):
+    (tmp_path / "pkg").mkdir()
+    (tmp_path / "pkg" / "defaults.py").write_text("BASE_STEPS = 25\nSCHEDULER = 'ddim'\n")
+    # Absolute imports are relative to the project root, even in a subdirectory
+    (tmp_path / "pkg" / "constants.py").write_text(
+        """
+from pkg.defaults import BASE_STEPS
+from .defaults import SCHEDULER
+
+DEFAULT_STEPS = BASE_STEPS * 2
+"""
+    )
+    code = """
+from cog import BasePredictor, Input
+from pkg.constants import DEFAULT_STEPS, SCHEDULER
+
+class Predictor(BasePredictor):
+    def predict(self, steps: int = DEFAULT_STEPS, scheduler: str = SCHEDULER) -> str:
+        return scheduler
+"""
+    schema = ast_openapi_schema.extract_info(code, tmp_path)
+    properties = schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["steps"]["default"] == 50
+    assert properties["scheduler"]["default"] == "ddim"
+
+
+def test_constants_of_dotted_module_imports(tmp_path):
+    (tmp_path / "pkg").mkdir()
+    (tmp_path / "pkg" / "constants.py").write_text("STEPS = 30\nSCHEDULER = 'ddim'\n")
+    code = """
+from cog import BasePredictor, Input
+import pkg.constants
+import pkg.constants as c
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        steps: int = Input(default=pkg.constants.STEPS),
+        scheduler: str = c.SCHEDULER,
+    ) -> str:
+        return scheduler
+"""
+    schema = ast_openapi_schema.extract_info(code, tmp_path)
+    properties = schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["steps"]["default"] == 30
+    assert properties["scheduler"]["default"] == "ddim"
+
+
+@uses_predictor("input_annotated")
+def test_annotated_input(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    default_form = _fixture_path("input_annotated_default_form").split(":", 1)[0]
+    assert static_schema == ast_openapi_schema.extract_file(default_form)
+
+    resp = client.post("/predictions", json={"input": {"prompt": "a cat"}})
+    assert resp.status_code == 200
+    assert resp.json()["output"] == "a cat ddim 50"
+
+    resp = client.post(
+        "/predictions", json={"input": {"prompt": "a cat", "steps": 0}}
+    )
+    assert resp.status_code == 422
+
+
+@uses_predictor("input_datetime")
+def test_datetime_input_and_output(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    schemas = static_schema["components"]["schemas"]
+    assert schemas["Input"]["properties"]["start"]["format"] == "date-time"
+    assert schemas["Input"]["properties"]["day"]["format"] == "date"
+    assert schemas["Output"] == {
+        "title": "Output",
+        "type": "string",
+        "format": "date-time",
+    }
+
+    resp = client.post(
+        "/predictions",
+        json={"input": {"start": "2024-03-01T12:30:00", "day": "2024-05-06"}},
+    )
+    assert resp.status_code == 200
+    assert resp.json()["output"] == "2024-05-06T12:30:00"
+
+    resp = client.post(
+        "/predictions", json={"input": {"start": "noon", "day": "2024-05-06"}}
+    )
+    assert resp.status_code == 422
+
+
+@uses_predictor("input_literal")
+def test_literal_choices(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    schemas = static_schema["components"]["schemas"]
+    assert schemas["mode"]["enum"] == ["fast", "slow"]
+    assert schemas["mode"]["type"] == "string"
+    assert schemas["steps"]["enum"] == [10, 20, 50]
+    assert schemas["steps"]["type"] == "integer"
+
+    resp = client.post("/predictions", json={"input": {"mode": "slow"}})
+    assert resp.status_code == 200
+    assert resp.json()["output"] == "slow 20"
+
+    resp = client.post("/predictions", json={"input": {"mode": "medium"}})
+    assert resp.status_code == 422
+
+
+def test_mixed_literal_choices_name_the_input():
+    code = """
+from typing import Literal
+
+class Predictor:
+    def predict(self, mode: Literal["fast", 1]) -> str:
+        return mode
+"""
+    with pytest.raises(ValueError, match="input mode must all be strings"):
+        ast_openapi_schema.extract_info(code)
+
+
+@uses_predictor("input_comments")
+def test_trailing_comments_are_descriptions(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    properties = static_schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["prompt"]["description"] == "What to generate"
+    assert properties["steps"]["description"] == "Number of denoising steps"
+    # An explicit description always wins
+    assert properties["seed"]["description"] == "Random seed"
+    # A comment shared by several parameters isn't used for any of them
+    assert "description" not in properties["width"]
+    assert "description" not in properties["height"]
+
+
+def test_choices_from_installed_package_are_unresolvable():
+    code = """
+from cog import BasePredictor, Input
+from diffusers_presets import SCHEDULERS
+
+class Predictor(BasePredictor):
+    def predict(self, scheduler: str = Input(choices=SCHEDULERS)) -> str:
+        return scheduler
+"""
+    with pytest.raises(
+        ValueError, match="Could not resolve choices=SCHEDULERS for input scheduler"
+    ):
+        ast_openapi_schema.extract_info(code)
+
+
+def test_static_schema_uses_the_class_in_the_predictor_ref():
+    code = """
+from cog import BasePredictor, Input
+
+class LoggingMixin:
+    def predict(
+        self,
+        message: str,  # Message to log
+    ) -> str:
+        return message
+
+class MyModel(LoggingMixin, BasePredictor):
+    def predict(
+        self,
+        steps: int = 50,  # Number of steps
+    ) -> int:
+        return steps
+"""
+    schema = ast_openapi_schema.extract_info(code, class_name="MyModel")
+    properties = schema["components"]["schemas"]["Input"]["properties"]
+    assert list(properties) == ["steps"]
+    assert properties["steps"]["description"] == "Number of steps"
+
+    with pytest.raises(ValueError, match="Could not find a class or function called Predictor"):
+        ast_openapi_schema.extract_info(code)
+
+
+def test_tool_comments_are_not_descriptions():
+    code = """
+class Predictor:
+    def predict(
+        self,
+        prompt: str,  # type: ignore
+        steps: int = 50,  # Number of steps  # noqa: E501
+        width: int = 512,  # pylint: disable=unused-argument
+        height: int = 512,  # fmt: skip
+    ) -> str:
+        return prompt
+"""
+    schema = ast_openapi_schema.extract_info(code)
+    properties = schema["components"]["schemas"]["Input"]["properties"]
+    assert properties["steps"]["description"] == "Number of steps"
+    assert "description" not in properties["prompt"]
+    assert "description" not in properties["width"]
+    assert "description" not in properties["height"]
+
+
+@uses_predictor("input_choices_derived")
+def test_choices_derived_from_module_dicts(client, static_schema):
+    resp = client.get("/openapi.json")
+    assert resp.status_code == 200
+    assert resp.json() == static_schema
+    schemas = static_schema["components"]["schemas"]
+    assert schemas["aspect_ratio"]["enum"] == ["1:1", "16:9", "9:16"]
+    assert schemas["scheduler"]["enum"] == ["ddim", "euler"]
+
+    resp = client.post("/predictions", json={"input": {"aspect_ratio": "16:9"}})
+    assert resp.status_code == 200
+    assert resp.json()["output"] == "1344x768 ddim"
+
+
+def test_choices_with_dict_unpacking_are_unresolvable():
+    code = """
+from cog import BasePredictor, Input
+
+BASE = {"ddim": "DDIM"}
+
+class Predictor(BasePredictor):
+    def predict(
+        self, scheduler: str = Input(choices={**BASE, "euler": "Euler"})
+    ) -> str:
+        return scheduler
+"""
+    with pytest.raises(ValueError, match="Could not resolve choices=.* for input scheduler"):
+        ast_openapi_schema.extract_info(code)
+
+
+def test_choices_and_defaults_imported_from_local_modules(tmp_path):
+    (tmp_path / "constants.py").write_text(
+        """
+DEFAULT_STEPS = 30
+SCHEDULERS = ["ddim", "euler"]
+SIZES = {512: "Small", 1024: "Large"}
+"""
+    )
+    code = """
+from cog import BasePredictor, Input
+import constants
+from .constants import SCHEDULERS, SIZES
+
+class Predictor(BasePredictor):
+    def predict(
+        self,
+        steps: int = Input(default=constants.DEFAULT_STEPS),
+        scheduler: str = Input(default="ddim", choices=SCHEDULERS),
+        size: int = Input(default=512, choices=SIZES),
+    ) -> str:
+        return scheduler
+"""
+    schema = ast_openapi_schema.extract_info(code, tmp_path)
+    schemas = schema["components"]["schemas"]
+    assert schemas["Input"]["properties"]["steps"]["default"] == 30
+    assert schemas["scheduler"]["enum"] == ["ddim", "euler"]
+    assert schemas["size"]["enum"] == [512, 1024]
+    assert schemas["size"]["x-enum-labels"] == ["Small", "Large"]
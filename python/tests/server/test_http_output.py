@@ -13,6 +13,33 @@ def test_return_wrong_type(client):
     assert resp.status_code == 500
 
 
+@uses_predictor("complex_output")
+def test_return_matching_shape(client):
+    resp = client.post("/predictions")
+    assert resp.status_code == 200
+    assert resp.json()["output"] == {"number": 42, "text": "meaning of life"}
+
+
+@uses_predictor("output_wrong_shape")
+def test_return_wrong_shape(client):
+    resp = client.post("/predictions")
+    assert resp.status_code == 500
+    body = resp.json()
+    assert body["detail"] == (
+        "The return value of predict() does not match its output type"
+    )
+    assert [e["field"] for e in body["errors"]] == ["text"]
+
+
+@uses_predictor_with_client_options(
+    "output_wrong_shape", env={"COG_LENIENT_OUTPUT": "1"}
+)
+def test_return_wrong_shape_lenient(client):
+    resp = client.post("/predictions")
+    assert resp.status_code == 200
+    assert resp.json()["output"] == {"number": 42}
+
+
 @uses_predictor("output_file")
 def test_output_file(client, match):
     res = client.post("/predictions")
@@ -3,8 +3,11 @@ import sys
 from typing import Optional
 from unittest.mock import patch
 
-from cog import File, Path
-from cog.predictor import get_weights_type, load_predictor_from_ref
+import pytest
+from cog import BasePredictor, File, Input, Path
+from cog.predictor import get_input_type, get_weights_type, load_predictor_from_ref
+from typing_extensions import Annotated
+from typing_extensions import Literal as ExtensionsLiteral
 
 
 def test_get_weights_type() -> None:
@@ -39,6 +42,58 @@ def test_load_predictor_from_ref_overrides_argv():
         assert sys.argv == ["foo.py", "exec", "--giraffes=2", "--eat-cookies"]
 
 
+def test_input_example_must_match_type():
+    class Predictor(BasePredictor):
+        def predict(self, steps: int = Input(example="fifty")) -> int:
+            return steps
+
+    with pytest.raises(TypeError, match="doesn't match its type int"):
+        get_input_type(Predictor())
+
+
+def test_integer_input_bounds_must_be_whole_numbers():
+    class Predictor(BasePredictor):
+        def predict(self, steps: int = Input(default=10, ge=1.0, le=50 / 3.0)) -> int:
+            return steps
+
+    with pytest.raises(TypeError, match="The le of parameter `steps` must be a whole number"):
+        get_input_type(Predictor())
+
+
+def test_annotated_input_alias_can_be_reused():
+    Scheduler = Annotated[str, Input(default="ddim", choices=["ddim", "euler"])]
+
+    class Predictor(BasePredictor):
+        def predict(self, first: Scheduler, second: Scheduler = "euler") -> str:
+            return first + second
+
+    # Each parameter gets its own default, and the choices survive building the
+    # input type more than once
+    for _ in range(2):
+        schema = get_input_type(Predictor()).schema()
+        assert schema["properties"]["first"]["default"] == "ddim"
+        assert schema["properties"]["second"]["default"] == "euler"
+        assert schema["definitions"]["first"]["enum"] == ["ddim", "euler"]
+        assert schema["definitions"]["second"]["enum"] == ["ddim", "euler"]
+
+
+@pytest.mark.skipif(sys.version_info < (3, 8), reason="Requires Python 3.8 or newer")
+def test_literal_from_typing_and_typing_extensions():
+    import typing
+
+    class Predictor(BasePredictor):
+        def predict(
+            self,
+            mode: typing.Literal["fast", "slow"],
+            steps: ExtensionsLiteral[10, 20],
+        ) -> str:
+            return f"{mode} {steps}"
+
+    schema = get_input_type(Predictor()).schema()
+    assert schema["definitions"]["mode"]["enum"] == ["fast", "slow"]
+    assert schema["definitions"]["steps"]["enum"] == [10, 20]
+
+
 def _fixture_path(name):
     test_dir = os.path.dirname(os.path.realpath(__file__))
     return os.path.join(test_dir, f"fixtures/{name}.py") + ":Predictor"
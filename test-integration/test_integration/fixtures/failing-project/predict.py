@@ -0,0 +1,7 @@
+from cog import BasePredictor
+
+
+class Predictor(BasePredictor):
+    def predict(self, s: str) -> str:
+        print("about to fail")
+        raise ValueError("bad input: " + s)
@@ -1,6 +1,9 @@
+import base64
+import gzip
 import json
 import os
 import subprocess
+import tarfile
 from pathlib import Path
 
 import pytest
@@ -220,3 +223,243 @@ def test_python_37_deprecated(docker_image):
         "minimum supported Python version is 3.8. requested 3.7"
         in build_process.stderr.decode()
     )
+
+
+def test_build_strict_rejects_dict_output(tmpdir, docker_image):
+    with open(tmpdir / "cog.yaml", "w") as f:
+        cog_yaml = """
+build:
+  python_version: "3.11"
+predict: predict.py:Predictor
+"""
+        f.write(cog_yaml)
+
+    with open(tmpdir / "predict.py", "w") as f:
+        code = """
+from cog import BasePredictor
+
+class Predictor(BasePredictor):
+    def predict(self, text: str) -> dict:
+        return {"text": text}
+"""
+        f.write(code)
+
+    build_process = subprocess.run(
+        ["cog", "build", "--strict", "-t", docker_image],
+        cwd=tmpdir,
+        capture_output=True,
+    )
+    assert build_process.returncode > 0
+    stderr = build_process.stderr.decode()
+    assert "Output in the output schema is a dict with no properties" in stderr
+    assert "build without --strict" in stderr
+
+    # Without --strict, the same model builds
+    subprocess.run(
+        ["cog", "build", "-t", docker_image],
+        cwd=tmpdir,
+        check=True,
+    )
+
+
+def test_build_squash(docker_image):
+    project_dir = Path(__file__).parent / "fixtures/path-project"
+    subprocess.run(
+        ["cog", "build", "--squash", "-t", docker_image],
+        cwd=project_dir,
+        check=True,
+    )
+    image = json.loads(
+        subprocess.run(
+            ["docker", "image", "inspect", docker_image],
+            capture_output=True,
+            check=True,
+        ).stdout
+    )
+    assert len(image[0]["RootFS"]["Layers"]) == 1
+    assert image[0]["Config"]["Labels"]["run.cog.has_init"] == "true"
+
+    # The squashed image still runs and has the same filesystem
+    output = subprocess.run(
+        ["docker", "run", "--rm", docker_image, "ls", "/src/predict.py"],
+        capture_output=True,
+        check=True,
+    ).stdout.decode()
+    assert "/src/predict.py" in output
+
+
+def test_build_progress_plain(docker_image):
+    project_dir = Path(__file__).parent / "fixtures/path-project"
+    build_process = subprocess.run(
+        ["cog", "build", "-t", docker_image, "--progress", "plain"],
+        cwd=project_dir,
+        capture_output=True,
+        check=True,
+    )
+    stderr = build_process.stderr.decode()
+    # plain output is line-oriented, so there should be no cursor movement or line clearing
+    assert "\x1b[" not in stderr
+    assert "\r" not in stderr
+
+
+def test_build_progress_json(docker_image):
+    project_dir = Path(__file__).parent / "fixtures/path-project"
+    build_process = subprocess.run(
+        ["cog", "build", "-t", docker_image, "--progress", "json"],
+        cwd=project_dir,
+        capture_output=True,
+        check=True,
+    )
+    events = []
+    for line in build_process.stderr.decode().splitlines():
+        if line.startswith("{"):
+            events.append(json.loads(line))
+    assert len(events) > 0
+
+
+def test_build_output_oci(docker_image, tmpdir):
+    project_dir = Path(__file__).parent / "fixtures/path-project"
+    dest = tmpdir / "image.tar"
+    subprocess.run(
+        ["cog", "build", "-t", docker_image, "--output-oci", str(dest)],
+        cwd=project_dir,
+        check=True,
+    )
+
+    with tarfile.open(dest) as tar:
+        names = tar.getnames()
+        assert "oci-layout" in names
+        index = json.load(tar.extractfile("index.json"))
+        assert len(index["manifests"]) == 1
+        manifest_digest = index["manifests"][0]["digest"].replace(":", "/")
+        manifest = json.load(tar.extractfile(f"blobs/{manifest_digest}"))
+        config_digest = manifest["config"]["digest"].replace(":", "/")
+        config = json.load(tar.extractfile(f"blobs/{config_digest}"))
+    assert "run.cog.config" in config["config"]["Labels"]
+
+
+def test_build_requirements_lock(docker_image, tmpdir):
+    project_dir = Path(__file__).parent / "fixtures/path-output-project"
+    dest = tmpdir / "requirements.lock"
+    subprocess.run(
+        ["cog", "build", "-t", docker_image, "--requirements-lock", str(dest)],
+        cwd=project_dir,
+        check=True,
+    )
+
+    lines = dest.read_text("utf-8").splitlines()
+    assert "pillow==8.3.2" in [line.lower() for line in lines]
+    # Cog is installed from a wheel that only exists during the build
+    assert not any(line.startswith("cog") for line in lines)
+
+
+def test_build_with_dockerfile(tmpdir, docker_image):
+    with open(tmpdir / "cog.yaml", "w") as f:
+        f.write(
+            """
+build:
+  python_version: "3.8"
+predict: predict.py:Predictor
+"""
+        )
+    with open(tmpdir / "Dockerfile", "w") as f:
+        f.write("FROM busybox\nRUN echo hello > /hello.txt\n")
+    # The image doesn't have Cog installed, so pass a schema rather than generating one
+    with open(tmpdir / "openapi.json", "w") as f:
+        json.dump(
+            {"openapi": "3.0.2", "info": {"title": "Cog", "version": "0.1.0"}, "paths": {}},
+            f,
+        )
+
+    subprocess.run(
+        [
+            "cog",
+            "build",
+            "-t",
+            docker_image,
+            "--dockerfile",
+            "Dockerfile",
+            "--openapi-schema",
+            "openapi.json",
+        ],
+        cwd=tmpdir,
+        check=True,
+    )
+
+    image = json.loads(
+        subprocess.run(
+            ["docker", "image", "inspect", docker_image],
+            capture_output=True,
+            check=True,
+        ).stdout
+    )
+    labels = image[0]["Config"]["Labels"]
+    assert "run.cog.version" in labels
+    assert json.loads(labels["run.cog.config"])["predict"] == "predict.py:Predictor"
+    assert json.loads(labels["run.cog.openapi_schema"])["openapi"] == "3.0.2"
+
+    output = subprocess.run(
+        ["docker", "run", "--rm", docker_image, "cat", "/hello.txt"],
+        capture_output=True,
+        check=True,
+    ).stdout
+    assert output == b"hello\n"
+
+
+def test_build_remove_after(tmpdir, docker_image):
+    with open(tmpdir / "cog.yaml", "w") as f:
+        f.write(
+            """
+build:
+  python_version: "3.8"
+  post_install:
+    - "mkdir -p build && touch build/a.o build/keep.txt"
+  remove_after:
+    - "/usr/share/doc"
+    - "build/*.o"
+predict: predict.py:Predictor
+"""
+        )
+    with open(tmpdir / "predict.py", "w") as f:
+        f.write(
+            """
+from cog import BasePredictor
+
+class Predictor(BasePredictor):
+    def predict(self, text: str) -> str:
+        return text
+"""
+        )
+
+    subprocess.run(["cog", "build", "-t", docker_image], cwd=tmpdir, check=True)
+
+    result = subprocess.run(
+        ["docker", "run", "--rm", docker_image, "ls", "-A", "/usr/share", "/src/build"],
+        capture_output=True,
+        text=True,
+        check=True,
+    )
+    assert "doc\n" not in result.stdout
+    assert "a.o" not in result.stdout
+    assert "keep.txt" in result.stdout
+
+
+def test_build_compress_schema_label(docker_image):
+    project_dir = Path(__file__).parent / "fixtures/path-project"
+    subprocess.run(
+        ["cog", "build", "-t", docker_image, "--compress-schema-label"],
+        cwd=project_dir,
+        check=True,
+    )
+    image = json.loads(
+        subprocess.run(
+            ["docker", "image", "inspect", docker_image],
+            capture_output=True,
+            check=True,
+        ).stdout
+    )
+    label = image[0]["Config"]["Labels"]["run.cog.openapi_schema"]
+    assert label.startswith("gzip+base64:")
+    schema = json.loads(gzip.decompress(base64.b64decode(label[len("gzip+base64:") :])))
+    assert schema["openapi"] == "3.0.2"
+    assert "Input" in schema["components"]["schemas"]
@@ -1,3 +1,4 @@
+import json
 import pathlib
 import shutil
 import subprocess
@@ -24,6 +25,35 @@ def test_predict_takes_string_inputs_and_returns_strings_to_stdout():
     assert "falling back to slow loader" in result.stderr
 
 
+def test_predict_json_output():
+    project_dir = Path(__file__).parent / "fixtures/string-project"
+    result = subprocess.run(
+        ["cog", "predict", "--json", "-i", "s=world"],
+        cwd=project_dir,
+        check=True,
+        capture_output=True,
+        text=True,
+        timeout=DEFAULT_TIMEOUT,
+    )
+    assert json.loads(result.stdout) == {"status": "succeeded", "output": "hello world"}
+
+
+def test_predict_json_output_on_failure():
+    project_dir = Path(__file__).parent / "fixtures/failing-project"
+    result = subprocess.run(
+        ["cog", "predict", "--json", "-i", "s=world"],
+        cwd=project_dir,
+        capture_output=True,
+        text=True,
+        timeout=DEFAULT_TIMEOUT,
+    )
+    assert result.returncode != 0
+    output = json.loads(result.stdout)
+    assert output["status"] == "failed"
+    assert output["error"] == "bad input: world"
+    assert "about to fail" in output["logs"]
+
+
 def test_predict_takes_int_inputs_and_returns_ints_to_stdout():
     project_dir = Path(__file__).parent / "fixtures/int-project"
     result = subprocess.run(
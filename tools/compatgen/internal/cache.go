@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anaskhan96/soup"
+)
+
+// CacheDir is where fetched pages are cached. Pages aren't cached if it's empty.
+var CacheDir string
+
+// CacheTTL is how long a cached page is used for before it's fetched again
+var CacheTTL = 24 * time.Hour
+
+// get fetches a page, replaced in tests
+var get = soup.Get
+
+type cacheEntry struct {
+	URL       string    `json:"url"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Body      string    `json:"body"`
+}
+
+// fetch returns the body of url, from the cache if there's a fresh enough copy
+func fetch(url string) (string, error) {
+	if CacheDir == "" {
+		return get(url)
+	}
+
+	path := cachePath(url)
+	entry, err := readCacheEntry(path)
+	if err != nil {
+		return "", err
+	}
+	if entry != nil && time.Since(entry.FetchedAt) < CacheTTL {
+		return entry.Body, nil
+	}
+
+	body, err := get(url)
+	if err != nil {
+		return "", err
+	}
+	if err := writeCacheEntry(path, cacheEntry{URL: url, FetchedAt: time.Now(), Body: body}); err != nil {
+		return "", err
+	}
+	return body, nil
+}
+
+func cachePath(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(CacheDir, hex.EncodeToString(hash[:])+".json")
+}
+
+// readCacheEntry returns nil if there isn't an entry at path
+func readCacheEntry(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read cache entry %s: %w", path, err)
+	}
+	entry := new(cacheEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		// A corrupt entry is the same as a missing one, it'll be overwritten
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func writeCacheEntry(path string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Failed to encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("Failed to create cache directory: %w", err)
+	}
+	// Write then rename so an interrupted run doesn't leave a truncated entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("Failed to write cache entry %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("Failed to write cache entry %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anaskhan96/soup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCache(t *testing.T) {
+	requests := 0
+	get = func(url string) (string, error) {
+		requests++
+		return fmt.Sprintf("response %d", requests), nil
+	}
+	CacheDir = t.TempDir()
+	CacheTTL = time.Hour
+	t.Cleanup(func() {
+		get = soup.Get
+		CacheDir = ""
+	})
+	url := "https://example.com/versions"
+
+	body, err := fetch(url)
+	require.NoError(t, err)
+	require.Equal(t, "response 1", body)
+
+	body, err = fetch(url)
+	require.NoError(t, err)
+	require.Equal(t, "response 1", body)
+	require.Equal(t, 1, requests)
+
+	// Expired entries are fetched again
+	CacheTTL = 0
+	body, err = fetch(url)
+	require.NoError(t, err)
+	require.Equal(t, "response 2", body)
+
+	CacheDir = ""
+	body, err = fetch(url)
+	require.NoError(t, err)
+	require.Equal(t, "response 3", body)
+}
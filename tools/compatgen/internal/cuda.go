@@ -6,8 +6,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/anaskhan96/soup"
-
 	"github.com/replicate/cog/pkg/config"
 )
 
@@ -33,7 +31,7 @@ func FetchCUDABaseImages() ([]config.CUDABaseImage, error) {
 func fetchCUDABaseImageTags(url string) ([]string, error) {
 	tags := []string{}
 
-	resp, err := soup.Get(url)
+	resp, err := fetch(url)
 	if err != nil {
 		return tags, fmt.Errorf("Failed to download %s: %w", url, err)
 	}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/anaskhan96/soup"
+
+	"github.com/replicate/cog/pkg/config"
+	"github.com/replicate/cog/pkg/util/version"
+)
+
+const jaxReleasesURL = "https://storage.googleapis.com/jax-releases/jax_cuda_releases.html"
+
+// e.g. cuda12/jaxlib-0.4.20+cuda12.cudnn89-cp311-cp311-manylinux2014_x86_64.whl
+var jaxlibWheelRegexp = regexp.MustCompile(`jaxlib-(([0-9.]+)\+cuda([0-9]+)\.cudnn([0-9]+))-cp([0-9]+)-cp[0-9]+m?-manylinux[0-9_]*_x86_64\.whl`)
+
+type jaxlibWheel struct {
+	Name          string
+	Version       string
+	CUDA          string
+	CuDNN         string
+	PythonVersion string
+}
+
+func FetchJaxCompatibilityMatrix() ([]config.JaxCompatibility, error) {
+	resp, err := fetch(jaxReleasesURL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download %s: %w", jaxReleasesURL, err)
+	}
+	doc := soup.HTMLParse(resp)
+
+	wheels := []jaxlibWheel{}
+	for _, link := range doc.FindAll("a") {
+		if wheel, ok := parseJaxlibWheel(link.Text()); ok {
+			wheels = append(wheels, wheel)
+		}
+	}
+	compats := jaxCompatibilities(wheels)
+
+	// sanity check
+	if len(compats) < 20 {
+		return nil, fmt.Errorf("JAX compatibility matrix only had %d rows, has the html changed?", len(compats))
+	}
+
+	return compats, nil
+}
+
+func parseJaxlibWheel(filename string) (wheel jaxlibWheel, ok bool) {
+	groups := jaxlibWheelRegexp.FindStringSubmatch(filename)
+	if len(groups) == 0 {
+		return wheel, false
+	}
+	name, jaxVersion, cuda, cuDNN, pythonVersion := groups[1], groups[2], groups[3], groups[4], groups[5]
+
+	cuDNN = parseCuDNNTag(cuDNN)
+	// 311 -> 3.11
+	pythonVersion = pythonVersion[:1] + "." + pythonVersion[1:]
+
+	return jaxlibWheel{
+		Name:          name,
+		Version:       jaxVersion,
+		CUDA:          cuda,
+		CuDNN:         cuDNN,
+		PythonVersion: pythonVersion,
+	}, true
+}
+
+// parseCuDNNTag turns the cuDNN version in a jaxlib wheel into a dotted version. The
+// major and minor versions are one digit each, and anything after them is the patch
+// version, e.g. 89 -> 8.9 and 805 -> 8.0.5
+func parseCuDNNTag(tag string) string {
+	if len(tag) < 2 {
+		return tag
+	}
+	cuDNN := tag[:1] + "." + tag[1:2]
+	if len(tag) > 2 {
+		cuDNN += "." + tag[2:]
+	}
+	return cuDNN
+}
+
+// jaxCompatibilities groups wheels for the same jaxlib build, skipping CUDA versions we
+// don't have base images for. Newest versions come first.
+func jaxCompatibilities(wheels []jaxlibWheel) []config.JaxCompatibility {
+	minCudaVersion := strconv.Itoa(config.MinimumMajorCudaVersion)
+
+	compatsByName := map[string]*config.JaxCompatibility{}
+	compats := []*config.JaxCompatibility{}
+	for _, wheel := range wheels {
+		if !version.GreaterOrEqual(wheel.CUDA, minCudaVersion) {
+			continue
+		}
+		compat, ok := compatsByName[wheel.Name]
+		if !ok {
+			compat = &config.JaxCompatibility{
+				Jax:           wheel.Version,
+				JaxlibPackage: "jaxlib==" + wheel.Name,
+				FindLinks:     jaxReleasesURL,
+				CUDA:          wheel.CUDA,
+				CuDNN:         wheel.CuDNN,
+			}
+			compatsByName[wheel.Name] = compat
+			compats = append(compats, compat)
+		}
+		if !slices.Contains(compat.Pythons, wheel.PythonVersion) {
+			compat.Pythons = append(compat.Pythons, wheel.PythonVersion)
+		}
+	}
+
+	sort.SliceStable(compats, func(i, j int) bool {
+		a, b := compats[i], compats[j]
+		if a.Jax != b.Jax {
+			return version.Greater(a.Jax, b.Jax)
+		}
+		if a.CUDA != b.CUDA {
+			return version.Greater(a.CUDA, b.CUDA)
+		}
+		return version.Greater(a.CuDNN, b.CuDNN)
+	})
+
+	result := []config.JaxCompatibility{}
+	for _, compat := range compats {
+		sortPythonVersions(compat.Pythons)
+		result = append(result, *compat)
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/config"
+)
+
+func TestJaxCompatibilities(t *testing.T) {
+	wheels := []jaxlibWheel{}
+	for _, filename := range []string{
+		"cuda11/jaxlib-0.4.20+cuda11.cudnn86-cp311-cp311-manylinux2014_x86_64.whl",
+		"cuda12/jaxlib-0.4.20+cuda12.cudnn89-cp39-cp39-manylinux2014_x86_64.whl",
+		"cuda12/jaxlib-0.4.20+cuda12.cudnn89-cp311-cp311-manylinux2014_x86_64.whl",
+		"cuda12/jaxlib-0.4.20+cuda12.cudnn89-cp310-cp310-manylinux2014_x86_64.whl",
+		"cuda11/jaxlib-0.4.9+cuda11.cudnn86-cp38-cp38-manylinux2014_x86_64.whl",
+		"cuda/jaxlib-0.1.57+cuda110-cp38-none-manylinux2010_x86_64.whl",
+	} {
+		if wheel, ok := parseJaxlibWheel(filename); ok {
+			wheels = append(wheels, wheel)
+		}
+	}
+
+	findLinks := "https://storage.googleapis.com/jax-releases/jax_cuda_releases.html"
+	require.Equal(t, []config.JaxCompatibility{{
+		Jax:           "0.4.20",
+		JaxlibPackage: "jaxlib==0.4.20+cuda12.cudnn89",
+		FindLinks:     findLinks,
+		CUDA:          "12",
+		CuDNN:         "8.9",
+		Pythons:       []string{"3.9", "3.10", "3.11"},
+	}, {
+		Jax:           "0.4.20",
+		JaxlibPackage: "jaxlib==0.4.20+cuda11.cudnn86",
+		FindLinks:     findLinks,
+		CUDA:          "11",
+		CuDNN:         "8.6",
+		Pythons:       []string{"3.11"},
+	}, {
+		Jax:           "0.4.9",
+		JaxlibPackage: "jaxlib==0.4.9+cuda11.cudnn86",
+		FindLinks:     findLinks,
+		CUDA:          "11",
+		CuDNN:         "8.6",
+		Pythons:       []string{"3.8"},
+	}}, jaxCompatibilities(wheels))
+}
+
+func TestParseCuDNNTag(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"89":  "8.9",
+		"91":  "9.1",
+		"805": "8.0.5",
+		"8":   "8",
+	} {
+		require.Equal(t, expected, parseCuDNNTag(tag), tag)
+	}
+}
@@ -3,6 +3,8 @@ package internal
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/anaskhan96/soup"
@@ -10,6 +12,7 @@ import (
 	"github.com/hashicorp/go-version"
 
 	"github.com/replicate/cog/pkg/config"
+	"github.com/replicate/cog/pkg/util/console"
 )
 
 type torchPackage struct {
@@ -17,13 +20,18 @@ type torchPackage struct {
 	Version       string
 	Variant       string
 	CUDA          *string
+	ROCm          *string
 	PythonVersion string
 }
 
 func FetchTorchCompatibilityMatrix() ([]config.TorchCompatibility, error) {
+	torchPackages, err := fetchTorchPackages("torch")
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching PyTorch packages: %w", err)
+	}
+
 	compats := []config.TorchCompatibility{}
-	var err error
-	compats, err = fetchCurrentTorchVersions(compats)
+	compats, err = fetchCurrentTorchVersions(compats, torchPackages)
 	if err != nil {
 		return nil, err
 	}
@@ -31,20 +39,28 @@ func FetchTorchCompatibilityMatrix() ([]config.TorchCompatibility, error) {
 	if err != nil {
 		return nil, err
 	}
+	compats = setTorchPythons(compats, torchPythons(torchPackages))
 
 	// sanity check
 	if len(compats) < 21 {
 		return nil, fmt.Errorf("PyTorch compatibility matrix only had %d rows, has the html changed?", len(compats))
 	}
+	rocmRows := 0
+	for _, compat := range compats {
+		if compat.ROCm != nil {
+			rocmRows++
+		}
+	}
+	if rocmRows < 5 {
+		return nil, fmt.Errorf("PyTorch compatibility matrix only had %d ROCm rows, has the html changed?", rocmRows)
+	}
 
 	return compats, nil
 }
 
 func fetchTorchPackages(name string) ([]torchPackage, error) {
-	pkgRegexp := regexp.MustCompile(`(.+?)-(([0-9.]+)\+([a-z0-9]+))-cp([0-9.]+)-cp([0-9.]+)-linux_x86_64.whl`)
-
 	url := fmt.Sprintf("https://download.pytorch.org/whl/%s/", name)
-	resp, err := soup.Get(url)
+	resp, err := fetch(url)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to download %s: %w", url, err)
 	}
@@ -52,38 +68,109 @@ func fetchTorchPackages(name string) ([]torchPackage, error) {
 	links := doc.FindAll("a")
 	packages := []torchPackage{}
 	for _, link := range links {
-		groups := pkgRegexp.FindStringSubmatch(link.Text())
-		if len(groups) == 0 {
-			continue
+		if pkg, ok := parseTorchWheel(link.Text()); ok {
+			packages = append(packages, pkg)
 		}
-		name, version, variant, pythonVersion := groups[2], groups[3], groups[4], groups[5]
-
-		var cuda *string
-		switch {
-		case variant == "cpu":
-			cuda = nil
-		case strings.HasPrefix(variant, "cu"):
-			// cu92 -> 9.2
-			c := strings.TrimPrefix(variant, "cu")
-			c = c[:len(c)-1] + "." + c[len(c)-1:]
-			cuda = &c
-		default:
-			// rocm etc
+	}
+	return packages, nil
+}
+
+var torchWheelRegexp = regexp.MustCompile(`(.+?)-(([0-9.]+)\+([a-z0-9.]+))-cp([0-9]+)-cp([0-9]+)-linux_x86_64.whl`)
+
+// parseTorchWheel parses a wheel filename like torch-2.1.0+cu121-cp311-cp311-linux_x86_64.whl.
+// ok is false if it isn't a Linux wheel for a variant we know about.
+func parseTorchWheel(filename string) (pkg torchPackage, ok bool) {
+	groups := torchWheelRegexp.FindStringSubmatch(filename)
+	if len(groups) == 0 {
+		return pkg, false
+	}
+	name, version, variant, pythonVersion := groups[2], groups[3], groups[4], groups[5]
+
+	cuda, rocm, ok := parseTorchVariant(variant)
+	if !ok {
+		return pkg, false
+	}
+
+	// 310 -> 3.10
+	pythonVersion = pythonVersion[:1] + "." + pythonVersion[1:]
+
+	return torchPackage{
+		Name:          name,
+		Version:       version,
+		Variant:       variant,
+		CUDA:          cuda,
+		ROCm:          rocm,
+		PythonVersion: pythonVersion,
+	}, true
+}
+
+// torchPythons returns the Python versions there are wheels for, keyed by both the torch
+// version with its variant (2.1.0+cu121) and without it (2.1.0)
+func torchPythons(packages []torchPackage) map[string][]string {
+	pythons := map[string][]string{}
+	for _, pkg := range packages {
+		for _, key := range []string{pkg.Name, pkg.Version} {
+			if !slices.Contains(pythons[key], pkg.PythonVersion) {
+				pythons[key] = append(pythons[key], pkg.PythonVersion)
+			}
+		}
+	}
+	for _, v := range pythons {
+		sortPythonVersions(v)
+	}
+	return pythons
+}
+
+// setTorchPythons sets the Python versions for each torch version from the wheels that
+// were published for it. Versions with no Linux wheels are dropped, because we can't
+// install them anyway.
+func setTorchPythons(compats []config.TorchCompatibility, pythons map[string][]string) []config.TorchCompatibility {
+	result := []config.TorchCompatibility{}
+	for _, compat := range compats {
+		p, ok := pythons[compat.Torch]
+		if !ok {
+			torch, _, _ := strings.Cut(compat.Torch, "+")
+			p, ok = pythons[torch]
+		}
+		if !ok {
+			console.Warnf("No wheels found for torch %s", compat.Torch)
 			continue
 		}
+		compat.Pythons = p
+		result = append(result, compat)
+	}
+	return result
+}
 
-		// 310 -> 3.10
-		pythonVersion = pythonVersion[:1] + "." + pythonVersion[1:]
+// sortPythonVersions sorts versions like 3.9 and 3.10 numerically
+func sortPythonVersions(pythons []string) {
+	sort.Slice(pythons, func(i, j int) bool {
+		a, errA := version.NewVersion(pythons[i])
+		b, errB := version.NewVersion(pythons[j])
+		if errA != nil || errB != nil {
+			return pythons[i] < pythons[j]
+		}
+		return a.LessThan(b)
+	})
+}
 
-		packages = append(packages, torchPackage{
-			Name:          name,
-			Version:       version,
-			Variant:       variant,
-			CUDA:          cuda,
-			PythonVersion: pythonVersion,
-		})
+// parseTorchVariant returns the CUDA or ROCm version in a wheel's local version, like
+// cu118 or rocm5.6, or neither for cpu. ok is false for variants we don't know about.
+func parseTorchVariant(variant string) (cuda *string, rocm *string, ok bool) {
+	switch {
+	case variant == "cpu":
+		return nil, nil, true
+	case strings.HasPrefix(variant, "cu"):
+		// cu92 -> 9.2
+		c := strings.TrimPrefix(variant, "cu")
+		c = c[:len(c)-1] + "." + c[len(c)-1:]
+		return &c, nil, true
+	case strings.HasPrefix(variant, "rocm"):
+		// rocm5.6 -> 5.6
+		r := strings.TrimPrefix(variant, "rocm")
+		return nil, &r, true
 	}
-	return packages, nil
+	return nil, nil, false
 }
 
 func getLatestVersion(packages []torchPackage) string {
@@ -91,7 +178,7 @@ func getLatestVersion(packages []torchPackage) string {
 	for _, pkg := range packages {
 		v, err := version.NewVersion(pkg.Version)
 		if err != nil {
-			fmt.Println("error parsing version:", pkg.Version)
+			console.Warnf("Error parsing version: %s", pkg.Version)
 			continue
 		}
 		if v.GreaterThan(latestVersion) {
@@ -101,15 +188,11 @@ func getLatestVersion(packages []torchPackage) string {
 	return latestVersion.String()
 }
 
-func fetchCurrentTorchVersions(compats []config.TorchCompatibility) ([]config.TorchCompatibility, error) {
+func fetchCurrentTorchVersions(compats []config.TorchCompatibility, torchPackages []torchPackage) ([]config.TorchCompatibility, error) {
 	// For the latest PyTorch version, we can just grab the latest of each packages from the repository.
 	// We then install the packages in the same way as we do for 1.12.x:
 	// https://pytorch.org/get-started/previous-versions/#v1121
 
-	torchPackages, err := fetchTorchPackages("torch")
-	if err != nil {
-		return nil, fmt.Errorf("Error fetching PyTorch packages: %w", err)
-	}
 	torchVisionPackages, err := fetchTorchPackages("torchvision")
 	if err != nil {
 		return nil, fmt.Errorf("Error fetching PyTorch packages: %w", err)
@@ -139,6 +222,7 @@ func fetchCurrentTorchVersions(compats []config.TorchCompatibility) ([]config.To
 				Torchvision:   latestTorchvisionVersion,
 				Torchaudio:    latestTorchaudioVersion,
 				CUDA:          pkg.CUDA,
+				ROCm:          pkg.ROCm,
 				ExtraIndexURL: "https://download.pytorch.org/whl/" + pkg.Variant,
 				Pythons:       []string{pkg.PythonVersion},
 			}
@@ -153,7 +237,7 @@ func fetchCurrentTorchVersions(compats []config.TorchCompatibility) ([]config.To
 	return compats, nil
 }
 
-func parseTorchInstallString(s string, defaultVersions map[string]string, cuda *string) (*config.TorchCompatibility, error) {
+func parseTorchInstallString(s string, defaultVersions map[string]string, cuda *string, rocm *string) (*config.TorchCompatibility, error) {
 	// for example:
 	// pip3 install torch torchvision torchaudio --extra-index-url https://download.pytorch.org/whl/cu113
 	// pip install torch==1.8.0+cpu torchvision==0.9.0+cpu torchaudio==0.8.0 -f https://download.pytorch.org/whl/torch_stable.html
@@ -208,9 +292,7 @@ func parseTorchInstallString(s string, defaultVersions map[string]string, cuda *
 	}
 	torchaudio := libVersions["torchaudio"]
 
-	// TODO: this could be determined from https://download.pytorch.org/whl/torch/
-	pythons := []string{"3.7", "3.8", "3.9", "3.10", "3.11"}
-
+	// Pythons is set from the published wheels by setTorchPythons
 	return &config.TorchCompatibility{
 		Torch:         torch,
 		Torchvision:   torchvision,
@@ -218,7 +300,7 @@ func parseTorchInstallString(s string, defaultVersions map[string]string, cuda *
 		FindLinks:     findLinks,
 		ExtraIndexURL: extraIndexURL,
 		CUDA:          cuda,
-		Pythons:       pythons,
+		ROCm:          rocm,
 	}, nil
 }
 
@@ -228,7 +310,7 @@ func fetchPreviousTorchVersions(compats []config.TorchCompatibility) ([]config.T
 	// because we don't know what versions of torch, torchvision, and torchaudio are compatible with each other.
 
 	url := "https://pytorch.org/get-started/previous-versions/"
-	resp, err := soup.Get(url)
+	resp, err := fetch(url)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to download %s: %w", url, err)
 	}
@@ -257,38 +339,39 @@ func parsePreviousTorchVersionsCode(code string, compats []config.TorchCompatibi
 	}
 
 	var cuda *string
-	skipSection := false
+	var rocm *string
 
 	for _, line := range strings.Split(code, "\n") {
 		// Set section
 		if strings.HasPrefix(line, "#") {
-			skipSection = false
 			rawArch := strings.ToLower(line[2:])
 			switch {
 			case strings.HasPrefix(rawArch, "cuda"):
 				_, c := split2(rawArch, " ")
 				cuda = &c
+				rocm = nil
 			case rawArch == "cpu only":
 				cuda = nil
+				rocm = nil
 			case strings.HasPrefix(rawArch, "rocm"):
+				// e.g. ROCM 5.2 (Linux only)
+				fields := strings.Fields(rawArch)
+				if len(fields) < 2 {
+					return nil, fmt.Errorf("Failed to parse ROCm version from %q", line)
+				}
 				cuda = nil
-				skipSection = true
+				rocm = &fields[1]
 			default:
 				// Ignore additional heading lines (notes, etc)
 				continue
 			}
 		}
 
-		// In a ROCM section, so skip
-		if skipSection {
-			continue
-		}
-
 		// conda install etc
 		if !strings.HasPrefix(line, "pip install ") {
 			continue
 		}
-		compat, err := parseTorchInstallString(line, supportedLibrarySet, cuda)
+		compat, err := parseTorchInstallString(line, supportedLibrarySet, cuda, rocm)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/replicate/cog/pkg/config"
+)
+
+func TestParseTorchWheel(t *testing.T) {
+	pkg, ok := parseTorchWheel("torch-2.4.0+cu121-cp312-cp312-linux_x86_64.whl")
+	require.True(t, ok)
+	require.Equal(t, "2.4.0+cu121", pkg.Name)
+	require.Equal(t, "2.4.0", pkg.Version)
+	require.Equal(t, "cu121", pkg.Variant)
+	require.Equal(t, "12.1", *pkg.CUDA)
+	require.Nil(t, pkg.ROCm)
+	require.Equal(t, "3.12", pkg.PythonVersion)
+
+	pkg, ok = parseTorchWheel("torch-2.4.0+rocm6.1-cp313-cp313-linux_x86_64.whl")
+	require.True(t, ok)
+	require.Nil(t, pkg.CUDA)
+	require.Equal(t, "6.1", *pkg.ROCm)
+	require.Equal(t, "3.13", pkg.PythonVersion)
+
+	_, ok = parseTorchWheel("torch-2.4.0+cpu-cp312-cp312-win_amd64.whl")
+	require.False(t, ok)
+}
+
+func TestSetTorchPythons(t *testing.T) {
+	packages := []torchPackage{}
+	for _, filename := range []string{
+		"torch-2.4.0+cpu-cp310-cp310-linux_x86_64.whl",
+		"torch-2.4.0+cpu-cp312-cp312-linux_x86_64.whl",
+		"torch-2.4.0+cpu-cp39-cp39-linux_x86_64.whl",
+		"torch-2.4.0+cu121-cp313-cp313-linux_x86_64.whl",
+		"torch-1.13.1+cpu-cp311-cp311-linux_x86_64.whl",
+	} {
+		pkg, ok := parseTorchWheel(filename)
+		require.True(t, ok)
+		packages = append(packages, pkg)
+	}
+
+	compats := setTorchPythons([]config.TorchCompatibility{
+		{Torch: "2.4.0+cpu"},
+		{Torch: "2.4.0"},
+		{Torch: "1.13.1"},
+		{Torch: "1.0.0"},
+	}, torchPythons(packages))
+	require.Equal(t, []config.TorchCompatibility{
+		{Torch: "2.4.0+cpu", Pythons: []string{"3.9", "3.10", "3.12"}},
+		{Torch: "2.4.0", Pythons: []string{"3.9", "3.10", "3.12", "3.13"}},
+		{Torch: "1.13.1", Pythons: []string{"3.11"}},
+	}, compats)
+}
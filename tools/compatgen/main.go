@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -12,14 +14,25 @@ import (
 
 func main() {
 	var output string
+	var cacheTTL time.Duration
+	var noCache bool
 
 	var rootCmd = &cobra.Command{
-		Use:   "compatgen {cuda|torch|tensorflow}",
+		Use:   "compatgen {cuda|torch|tensorflow|jax}",
 		Short: "Generate compatibility matrix for Cog base images",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			target := args[0]
 
+			if !noCache {
+				cacheDir, err := os.UserCacheDir()
+				if err != nil {
+					console.Fatalf("Failed to find cache directory: %s", err)
+				}
+				internal.CacheDir = filepath.Join(cacheDir, "cog", "compatgen")
+				internal.CacheTTL = cacheTTL
+			}
+
 			var v interface{}
 			var err error
 
@@ -34,6 +47,11 @@ func main() {
 				if err != nil {
 					console.Fatalf("Failed to fetch TensorFlow compatibility matrix: %s", err)
 				}
+			case "jax":
+				v, err = internal.FetchJaxCompatibilityMatrix()
+				if err != nil {
+					console.Fatalf("Failed to fetch JAX compatibility matrix: %s", err)
+				}
 			case "torch":
 				v, err = internal.FetchTorchCompatibilityMatrix()
 				if err != nil {
@@ -60,6 +78,8 @@ func main() {
 	}
 
 	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output flag (optional)")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", internal.CacheTTL, "How long to reuse fetched pages for")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always fetch pages instead of using cached copies")
 	if err := rootCmd.Execute(); err != nil {
 		console.Fatalf(err.Error())
 	}